@@ -0,0 +1,110 @@
+package shellquote
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+)
+
+// ansibleParams are the key=value parameters Ansible's command and shell
+// modules take from their free-form string.
+var ansibleParams = []string{"chdir", "creates", "executable", "removes", "stdin", "stdin_add_newline", "strip_empty_ends", "warn"}
+
+// ParseAnsibleFreeForm splits the free-form string of an Ansible command or
+// shell task, as in command: make install chdir=/src, into the command's
+// words and its key=value parameters, like creates, removes or chdir.
+//
+// As Ansible does, the parameters are recognized anywhere in the string,
+// their values lose one level of surrounding quotes and have backslash
+// escapes decoded like $'...' strings. The rest is split like Python's
+// shlex.split for the command module, or with the default options if shell
+// is set, since the shell module passes it to sh unsplit. Jinja2
+// templates are not expanded.
+func ParseAnsibleFreeForm(line string, shell bool) (argv []string, params map[string]string, err error) {
+	tokens, err := SplitTokens(line, pythonShlexSplitOptions())
+	if err != nil {
+		return nil, nil, err
+	}
+	var raw []string
+	for _, tok := range tokens {
+		text := line[tok.Start:tok.End]
+		key, value, ok := strings.Cut(text, "=")
+		if !ok || !isAnsibleParam(key) {
+			raw = append(raw, text)
+			continue
+		}
+		if params == nil {
+			params = make(map[string]string)
+		}
+		if len(value) >= 2 && (value[0] == '\'' || value[0] == '"') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		params[key], _ = interpretEscapes(value, ansiEscapes)
+	}
+
+	opts := pythonShlexSplitOptions()
+	if shell {
+		opts = DefaultSplitOptions()
+	}
+	argv, err = SplitWithOptions(strings.Join(raw, " "), opts)
+	return argv, params, err
+}
+
+// JoinAnsibleFreeForm returns the free-form string of an Ansible command or
+// shell task running argv with the given key=value parameters. The words
+// are quoted like Join, except that a word that would be taken for one of
+// the parameters is single-quoted. The parameters follow in sorted order.
+func JoinAnsibleFreeForm(argv []string, params map[string]string) string {
+	var buf bytes.Buffer
+	opts := DefaultQuoteOptions()
+	for i, word := range argv {
+		if i != 0 {
+			buf.WriteByte(' ')
+		}
+		if key, _, ok := strings.Cut(word, "="); ok && isAnsibleParam(key) {
+			quoteSingle(word, &buf, opts)
+		} else {
+			quote(word, &buf, opts)
+		}
+	}
+
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if buf.Len() > 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(key)
+		buf.WriteByte('=')
+		quoteAnsibleValue(params[key], &buf)
+	}
+	return buf.String()
+}
+
+func isAnsibleParam(key string) bool {
+	i := sort.SearchStrings(ansibleParams, key)
+	return i < len(ansibleParams) && ansibleParams[i] == key
+}
+
+// quoteAnsibleValue quotes the value of a key=value parameter so that
+// Ansible reads it back unchanged.
+func quoteAnsibleValue(value string, buf *bytes.Buffer) {
+	switch {
+	case value != "" && !strings.ContainsAny(value, " \t\r\n'\"\\"):
+		buf.WriteString(value)
+	case !strings.ContainsAny(value, "'\\"):
+		buf.WriteString("'" + value + "'")
+	default:
+		buf.WriteByte('"')
+		for i := 0; i < len(value); i++ {
+			if c := value[i]; c == '"' || c == '\\' {
+				buf.WriteByte('\\')
+			}
+			buf.WriteByte(value[i])
+		}
+		buf.WriteByte('"')
+	}
+}
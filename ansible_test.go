@@ -0,0 +1,46 @@
+package shellquote
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAnsibleFreeForm(t *testing.T) {
+	for _, elem := range ansibleFreeFormTest {
+		argv, params, err := ParseAnsibleFreeForm(elem.input, elem.shell)
+		if err != nil {
+			t.Errorf("Input %q, got error %v", elem.input, err)
+		} else if !reflect.DeepEqual(argv, elem.argv) || !reflect.DeepEqual(params, elem.params) {
+			t.Errorf("Input %q, got %q %q, expected %q %q", elem.input, argv, params, elem.argv, elem.params)
+		}
+	}
+}
+
+func TestJoinAnsibleFreeForm(t *testing.T) {
+	argv := []string{"make", "creates=x", "it's", `C:\a "b"`}
+	params := map[string]string{"chdir": "/my src", "creates": "/out/it's", "removes": `a\"b`, "warn": "no"}
+	output := JoinAnsibleFreeForm(argv, params)
+	expected := `make 'creates=x' it\'s 'C:\a "b"' chdir='/my src' creates="/out/it's" removes="a\\\"b" warn=no`
+	if output != expected {
+		t.Errorf("got %q, expected %q", output, expected)
+	}
+	gotArgv, gotParams, err := ParseAnsibleFreeForm(output, false)
+	if err != nil || !reflect.DeepEqual(gotArgv, argv) || !reflect.DeepEqual(gotParams, params) {
+		t.Errorf("%q parsed back as %q %q (%v)", output, gotArgv, gotParams, err)
+	}
+}
+
+var ansibleFreeFormTest = []struct {
+	input  string
+	shell  bool
+	argv   []string
+	params map[string]string
+}{
+	{"/usr/bin/make_database.sh db_user db_name creates=/path/to/database", false,
+		[]string{"/usr/bin/make_database.sh", "db_user", "db_name"}, map[string]string{"creates": "/path/to/database"}},
+	{"chdir='/my dir' echo \"a \\\"b\\\"\" 'c d' x=1", false,
+		[]string{"echo", `a "b"`, "c d", "x=1"}, map[string]string{"chdir": "/my dir"}},
+	{"cat < /etc/hosts | grep \"$HOST\" removes=/tmp/x", true,
+		[]string{"cat", "<", "/etc/hosts", "|", "grep", "$HOST"}, map[string]string{"removes": "/tmp/x"}},
+	{"echo a\\tb", false, []string{"echo", "atb"}, nil},
+}
@@ -0,0 +1,20 @@
+package shellquote
+
+import "strings"
+
+var appleScriptReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+// QuoteAppleScript returns s as an AppleScript string literal, including
+// the surrounding double-quotes. Backslashes and double-quotes are
+// escaped, and so are newlines, carriage returns and tabs, so that the
+// literal fits on one line.
+func QuoteAppleScript(s string) string {
+	return `"` + appleScriptReplacer.Replace(s) + `"`
+}
+
+// JoinAppleScript quotes and joins args for sh according to opts, and
+// returns the result as an AppleScript string literal, ready to be used as
+// in do shell script <literal>. A nil opts quotes like Join.
+func JoinAppleScript(args []string, opts *QuoteOptions) string {
+	return QuoteAppleScript(JoinWithOptions(args, opts))
+}
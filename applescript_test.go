@@ -0,0 +1,22 @@
+package shellquote
+
+import "testing"
+
+func TestJoinAppleScript(t *testing.T) {
+	for _, elem := range joinAppleScriptTest {
+		output := JoinAppleScript(elem.input, nil)
+		if output != elem.output {
+			t.Errorf("Input %q, got %q, expected %q", elem.input, output, elem.output)
+		}
+	}
+}
+
+var joinAppleScriptTest = []struct {
+	input  []string
+	output string
+}{
+	{[]string{"ls"}, `"ls"`},
+	{[]string{"open", "/Users/me/My File.txt"}, `"open '/Users/me/My File.txt'"`},
+	{[]string{"echo", `say "hi"`, "it's", `C:\x`}, `"echo 'say \"hi\"' it\\'s C:\\\\x"`},
+	{[]string{"printf", "a\tb\n"}, `"printf 'a\tb\n'"`},
+}
@@ -0,0 +1,240 @@
+package shellquote
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Bashism describes a construct found by CheckPOSIX that bash accepts but a
+// POSIX shell such as dash does not.
+type Bashism struct {
+	// Construct is the text that identified the construct, like "$'" or
+	// "<<<".
+	Construct string
+	// Message briefly describes the construct.
+	Message string
+	// Offset is the byte offset of the construct in the input.
+	Offset int
+}
+
+func (b Bashism) String() string {
+	return fmt.Sprintf("offset %d: %s (%s)", b.Offset, b.Message, b.Construct)
+}
+
+// CheckPOSIX scans input for bash-only constructs and returns them in the
+// order they appear. It recognizes $'...' and $"..." quoting, here-strings, the &>
+// and |& redirections, arrays, [[ tests, the function keyword, process
+// substitution, brace expansion, $[ arithmetic and the bash-specific forms of
+// ${} parameter expansion. The input is scanned with sh's quoting rules, so
+// quoted text is never reported; input that fails to split is still scanned
+// as far as possible.
+func CheckPOSIX(input string) []Bashism {
+	var found []Bashism
+	add := func(offset int, construct, message string) {
+		found = append(found, Bashism{Construct: construct, Message: message, Offset: offset})
+	}
+
+	wordStart := true
+	for i := 0; i < len(input); {
+		c := input[i]
+		rest := input[i:]
+		atWordStart := wordStart
+		wordStart = false
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == ';' || c == '(' || c == ')':
+			wordStart = true
+			i++
+		case c == '#' && atWordStart:
+			if end := strings.IndexByte(rest, '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(input)
+			}
+		case c == '\\':
+			i += 2
+		case c == '\'':
+			i = skipSingleQuoted(input, i)
+		case c == '"':
+			i = checkDoubleQuoted(input, i, add)
+		case strings.HasPrefix(rest, "$'"):
+			add(i, "$'", "ANSI-C quoting")
+			i = skipANSIQuoted(input, i+1)
+		case strings.HasPrefix(rest, "$\""):
+			add(i, "$\"", "locale-translated string")
+			i = checkDoubleQuoted(input, i+1, add)
+		case strings.HasPrefix(rest, "$["):
+			add(i, "$[", "obsolete arithmetic expansion")
+			i += 2
+		case strings.HasPrefix(rest, "${"):
+			checkParameter(input, i, add)
+			i += 2
+		case strings.HasPrefix(rest, "<<<"):
+			add(i, "<<<", "here-string")
+			i += 3
+		case strings.HasPrefix(rest, "&>"):
+			add(i, "&>", "redirection of stdout and stderr")
+			i += 2
+		case strings.HasPrefix(rest, "|&"):
+			add(i, "|&", "pipe of stdout and stderr")
+			i += 2
+		case strings.HasPrefix(rest, "<(") || strings.HasPrefix(rest, ">("):
+			add(i, rest[:2], "process substitution")
+			wordStart = true
+			i += 2
+		case c == '|' || c == '&' || c == '<' || c == '>':
+			wordStart = true
+			i++
+		case c == '{' && !atWordStart && isBraceExpansion(rest):
+			add(i, "{", "brace expansion")
+			i++
+		case atWordStart:
+			i = checkWordStart(input, i, add)
+		default:
+			i++
+		}
+	}
+	return found
+}
+
+// checkWordStart checks for constructs that are only recognized at the start
+// of a word and returns the position to continue scanning from.
+func checkWordStart(input string, i int, add func(int, string, string)) int {
+	rest := input[i:]
+	word := rest
+	if end := strings.IndexAny(rest, " \t\n;&|<>()"); end >= 0 {
+		word = rest[:end]
+	}
+	switch {
+	case word == "[[" || word == "]]":
+		add(i, word, "extended test command")
+		return i + 2
+	case word == "function":
+		add(i, word, "function keyword")
+		return i + len(word)
+	}
+
+	n := 0
+	for n < len(rest) && (isNameChar(rest[n]) && (n > 0 || isNameStart(rest[n]))) {
+		n++
+	}
+	if n == 0 {
+		if rest[0] == '{' && isBraceExpansion(rest) {
+			add(i, "{", "brace expansion")
+		}
+		return i + 1
+	}
+	switch {
+	case strings.HasPrefix(rest[n:], "=("):
+		add(i, rest[:n+2], "array assignment")
+		return i + n + 2
+	case strings.HasPrefix(rest[n:], "+="):
+		add(i, rest[:n+2], "append assignment")
+		return i + n + 2
+	case strings.HasPrefix(rest[n:], "["):
+		if end := strings.Index(rest[n:], "]"); end > 0 && strings.HasPrefix(rest[n+end+1:], "=") {
+			add(i, rest[:n+1], "array element assignment")
+		}
+	}
+	return i + n
+}
+
+// checkParameter checks the ${ parameter expansion at input[i] for
+// bash-only forms.
+func checkParameter(input string, i int, add func(int, string, string)) {
+	rest := input[i+2:]
+	if strings.HasPrefix(rest, "!") && !strings.HasPrefix(rest, "!}") {
+		add(i, "${!", "indirect expansion")
+		return
+	}
+	n := 0
+	if strings.HasPrefix(rest, "#") && len(rest) > 1 && rest[1] != '}' {
+		// ${#name} is POSIX
+		n = 1
+	}
+	start := n
+	for n < len(rest) && isNameChar(rest[n]) {
+		n++
+	}
+	if n == start && n < len(rest) && strings.IndexByte("@*#?$!-", rest[n]) >= 0 {
+		n++
+	}
+	if n >= len(rest) {
+		return
+	}
+	construct := input[i : i+2+n+1]
+	switch rest[n] {
+	case '[':
+		add(i, construct, "array subscript")
+	case '/':
+		add(i, construct, "pattern substitution")
+	case '^', ',':
+		add(i, construct, "case modification")
+	case '@':
+		add(i, construct, "parameter transformation")
+	case ':':
+		if n+1 < len(rest) && strings.IndexByte("-=?+", rest[n+1]) < 0 {
+			add(i, construct+rest[n+1:n+2], "substring expansion")
+		}
+	}
+}
+
+// checkDoubleQuoted scans the double-quoted string starting at input[i] for
+// bash-only parameter expansions and returns the position after it.
+func checkDoubleQuoted(input string, i int, add func(int, string, string)) int {
+	for i++; i < len(input); i++ {
+		switch input[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		case '$':
+			if strings.HasPrefix(input[i:], "${") {
+				checkParameter(input, i, add)
+			} else if strings.HasPrefix(input[i:], "$[") {
+				add(i, "$[", "obsolete arithmetic expansion")
+			}
+		}
+	}
+	return i
+}
+
+// skipDoubleQuoted returns the position after the double-quoted string
+// starting at input[i].
+func skipDoubleQuoted(input string, i int) int {
+	return checkDoubleQuoted(input, i, func(int, string, string) {})
+}
+
+// skipSingleQuoted returns the position after the single-quoted string
+// starting at input[i].
+func skipSingleQuoted(input string, i int) int {
+	if end := strings.IndexByte(input[i+1:], '\''); end >= 0 {
+		return i + 1 + end + 1
+	}
+	return len(input)
+}
+
+// skipANSIQuoted returns the position after the $'...' string whose opening
+// quote is at input[i]. Backslash escapes the closing quote.
+func skipANSIQuoted(input string, i int) int {
+	for i++; i < len(input); i++ {
+		switch input[i] {
+		case '\\':
+			i++
+		case '\'':
+			return i + 1
+		}
+	}
+	return len(input)
+}
+
+// isBraceExpansion reports whether s starts with a brace expansion like
+// {a,b} or {1..3}.
+func isBraceExpansion(s string) bool {
+	end := strings.IndexAny(s, "} \t\n;&|<>()'\"")
+	if end < 0 || s[end] != '}' {
+		return false
+	}
+	body := s[1:end]
+	return strings.Contains(body, ",") || strings.Contains(body, "..")
+}
@@ -0,0 +1,40 @@
+package shellquote
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckPOSIX(t *testing.T) {
+	for _, elem := range checkPOSIXTest {
+		var found []string
+		var offsets []int
+		for _, b := range CheckPOSIX(elem.input) {
+			found = append(found, b.Construct)
+			offsets = append(offsets, b.Offset)
+		}
+		if !reflect.DeepEqual(found, elem.constructs) || !reflect.DeepEqual(offsets, elem.offsets) {
+			t.Errorf("Input %q, got %q at %v, expected %q at %v", elem.input, found, offsets, elem.constructs, elem.offsets)
+		}
+	}
+}
+
+var checkPOSIXTest = []struct {
+	input      string
+	constructs []string
+	offsets    []int
+}{
+	{"echo hello; ls -l | wc -l > /dev/null 2>&1", nil, nil},
+	{"echo $'a\\'b' done", []string{"$'"}, []int{5}},
+	{"echo $\"hello\"", []string{"$\""}, []int{5}},
+	{"cat <<< \"$x\"", []string{"<<<"}, []int{4}},
+	{"cmd &> log; cmd |& tee", []string{"&>", "|&"}, []int{4, 16}},
+	{"arr=(a b) arr[1]=c x+=y", []string{"arr=(", "arr[", "x+="}, []int{0, 10, 19}},
+	{"[[ -f x ]] && function f", []string{"[[", "]]", "function"}, []int{0, 8, 14}},
+	{"diff <(a) >(b)", []string{"<(", ">("}, []int{5, 10}},
+	{"echo a{b,c} {1..3} {} {x}", []string{"{", "{"}, []int{6, 12}},
+	{"echo ${a[1]} ${!p} ${a/x/y} ${a^^} ${a:1:2} ${a@Q}", []string{"${a[", "${!", "${a/", "${a^", "${a:1", "${a@"}, []int{5, 13, 19, 28, 35, 44}},
+	{"echo ${a:-x} ${#a} ${a%%.*} ${#} ${!}", nil, nil},
+	{"echo \"${a/x/y}\" '${a/x/y} <<<' \\<<< $[1+2]", []string{"${a/", "$["}, []int{6, 36}},
+	{"# a comment with <<< and [[\necho ok", nil, nil},
+}
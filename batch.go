@@ -0,0 +1,113 @@
+package shellquote
+
+import (
+	"bytes"
+	"strings"
+)
+
+// BatchOptions configures QuoteBatch and JoinBatch.
+type BatchOptions struct {
+	// DelayedExpansion escapes words for batch files that run with delayed
+	// expansion enabled, as after setlocal EnableDelayedExpansion. There, a
+	// ! expands variables, and words containing one lose another level of
+	// caret escapes.
+	DelayedExpansion bool
+}
+
+func DefaultBatchOptions() *BatchOptions {
+	return &BatchOptions{}
+}
+
+// JoinBatch quotes each argument for use in a Windows batch file (.bat or
+// .cmd) and joins them with a space, so that a program started by the
+// resulting line receives the original arguments.
+//
+// Each argument is first quoted the way the Microsoft C runtime and
+// CommandLineToArgvW split command lines. The result is then escaped for
+// cmd.exe: a % is doubled, and the caret-escape is added in front of
+// cmd's special characters, including every double-quote, so that cmd
+// never considers itself inside a quoted string. Line breaks are written
+// as a caret followed by two line breaks.
+//
+// The output is only valid in batch files. On an interactive cmd prompt, a
+// doubled % is not reduced to one.
+func JoinBatch(args []string, opts *BatchOptions) string {
+	if opts == nil {
+		opts = DefaultBatchOptions()
+	}
+	var buf, arg bytes.Buffer
+	for i, word := range args {
+		if i != 0 {
+			buf.WriteByte(' ')
+		}
+		arg.Reset()
+		quoteMSVCRT(word, &arg)
+		escapeBatch(arg.String(), &buf, opts)
+	}
+	return buf.String()
+}
+
+// QuoteBatch quotes a single argument like JoinBatch.
+func QuoteBatch(word string, opts *BatchOptions) string {
+	return JoinBatch([]string{word}, opts)
+}
+
+// quoteMSVCRT quotes word so that the Microsoft C runtime reads it back as
+// a single argument. Backslashes are only special in front of a
+// double-quote, so only those, and the ones before the closing quote, are
+// doubled.
+func quoteMSVCRT(word string, buf *bytes.Buffer) {
+	if len(word) > 0 && !strings.ContainsAny(word, " \t\n\v\"") {
+		buf.WriteString(word)
+		return
+	}
+	buf.WriteByte('"')
+	slashes := 0
+	for i := 0; i < len(word); i++ {
+		switch word[i] {
+		case '\\':
+			slashes++
+		case '"':
+			buf.WriteString(strings.Repeat("\\", slashes+1))
+			slashes = 0
+		default:
+			slashes = 0
+		}
+		buf.WriteByte(word[i])
+	}
+	buf.WriteString(strings.Repeat("\\", slashes))
+	buf.WriteByte('"')
+}
+
+// batchSpecialChars are the characters cmd.exe interprets outside of
+// double-quotes.
+const batchSpecialChars = "^&|<>()\""
+
+// escapeBatch escapes arg for cmd.exe running a batch file.
+func escapeBatch(arg string, buf *bytes.Buffer, opts *BatchOptions) {
+	bang := false
+	for i := 0; i < len(arg); i++ {
+		if i == 0 || arg[i-1] == ' ' || arg[i-1] == '\t' {
+			// delayed expansion removes another level of carets from every
+			// whitespace separated token containing a !
+			end := strings.IndexAny(arg[i:], " \t")
+			if end < 0 {
+				end = len(arg) - i
+			}
+			bang = opts.DelayedExpansion && strings.IndexByte(arg[i:i+end], '!') >= 0
+		}
+		switch c := arg[i]; {
+		case c == '%':
+			buf.WriteByte('%')
+		case c == '\n':
+			buf.WriteString("^\n")
+		case bang && c == '!':
+			buf.WriteString("^^")
+		case bang && c == '^':
+			buf.WriteString("^^^")
+		case strings.IndexByte(batchSpecialChars, c) >= 0:
+			buf.WriteByte('^')
+		}
+		buf.WriteByte(arg[i])
+	}
+}
@@ -0,0 +1,26 @@
+package shellquote
+
+import "testing"
+
+func TestJoinBatch(t *testing.T) {
+	for _, elem := range joinBatchTest {
+		output := JoinBatch(elem.input, &BatchOptions{DelayedExpansion: elem.delayed})
+		if output != elem.output {
+			t.Errorf("Input %q, got %q, expected %q", elem.input, output, elem.output)
+		}
+	}
+}
+
+var joinBatchTest = []struct {
+	input   []string
+	delayed bool
+	output  string
+}{
+	{[]string{"prog", "a b", "", "100%"}, false, `prog ^"a b^" ^"^" 100%%`},
+	{[]string{`say "hi"`, "a&b|c", "(x^y)<z>"}, false, `^"say \^"hi\^"^" a^&b^|c ^(x^^y^)^<z^>`},
+	{[]string{`C:\dir\`, `C:\my dir\`, `a\\"b`}, false, `C:\dir\ ^"C:\my dir\\^" ^"a\\\\\^"b^"`},
+	{[]string{"a\nb"}, false, "^\"a^\n\nb^\""},
+	{[]string{"hi!", "a^b!", "a^b", "%x%!"}, false, `hi! a^^b! a^^b %%x%%!`},
+	{[]string{"hi!", "a^b!", "a^b", "%x%!"}, true, `hi^^! a^^^^b^^! a^^b %%x%%^^!`},
+	{[]string{"x! y^"}, true, `^"x^^! y^^^"`},
+}
@@ -27,3 +27,69 @@ func TestJoinSplit(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestPrintfStyleSplit(t *testing.T) {
+	split, _ := Preset("bash")
+	opts := &QuoteOptions{Style: PrintfStyle}
+	f := func(strs []string) bool {
+		combined := JoinWithOptions(strs, opts)
+		output, err := SplitWithOptions(combined, split)
+		if err != nil {
+			t.Logf("Error splitting %#v: %v", combined, err)
+			return false
+		}
+		if !reflect.DeepEqual(strs, output) {
+			t.Logf("Input %q did not match output %q", strs, output)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestShortestStyleSplit(t *testing.T) {
+	opts := &QuoteOptions{Style: ShortestStyle}
+	f := func(strs []string) bool {
+		combined := JoinWithOptions(strs, opts)
+		split, err := Split(combined)
+		if err != nil {
+			t.Logf("Error splitting %#v: %v", combined, err)
+			return false
+		}
+		if !reflect.DeepEqual(strs, split) {
+			t.Logf("Input %q did not match output %q", strs, split)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestASCIIOnlySplit(t *testing.T) {
+	split, _ := Preset("bash")
+	opts := &QuoteOptions{ASCIIOnly: true}
+	f := func(strs []string) bool {
+		combined := JoinWithOptions(strs, opts)
+		if !isPrintableASCII(combined) {
+			t.Logf("Output %q is not printable ASCII", combined)
+			return false
+		}
+		output, err := SplitWithOptions(combined, split)
+		if err != nil {
+			t.Logf("Error splitting %#v: %v", combined, err)
+			return false
+		}
+		if !reflect.DeepEqual(strs, output) {
+			t.Logf("Input %q did not match output %q", strs, output)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,123 @@
+// Command shellquote splits and quotes command lines from the shell.
+//
+// Usage:
+//
+//	shellquote split [-dialect name] [-0 | -json] < line
+//	shellquote join [-style name] [args...]
+//	shellquote quote [-style name] [args...]
+//
+// split reads a command line from standard input and prints its words, one
+// per line, separated by NUL bytes with -0, or as a JSON array with -json.
+// -dialect selects a preset like posix, bash or cmd. join quotes its
+// arguments and prints them on a single line, and quote prints each
+// argument quoted on a line of its own. -style selects the quoting style:
+// default, printf or shortest.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	shellquote "github.com/nmeilick/go-shellquote"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+const usage = `usage: shellquote split [-dialect name] [-0 | -json] < line
+       shellquote join [-style name] [args...]
+       shellquote quote [-style name] [args...]
+`
+
+var styles = map[string]shellquote.QuoteStyle{
+	"default":  shellquote.DefaultStyle,
+	"printf":   shellquote.PrintfStyle,
+	"shortest": shellquote.ShortestStyle,
+}
+
+// run runs the command line args and returns the exit status.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	fs := flag.NewFlagSet("shellquote "+args[0], flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	switch args[0] {
+	case "split":
+		dialect := fs.String("dialect", "posix", "split like the named `preset`: "+strings.Join(shellquote.PresetNames(), ", "))
+		nul := fs.Bool("0", false, "separate words with NUL bytes")
+		asJSON := fs.Bool("json", false, "print the words as a JSON array")
+		if fs.Parse(args[1:]) != nil {
+			return 2
+		}
+		if fs.NArg() > 0 || *nul && *asJSON {
+			fmt.Fprint(stderr, usage)
+			return 2
+		}
+		return split(stdin, stdout, stderr, *dialect, *nul, *asJSON)
+	case "join", "quote":
+		style := fs.String("style", "default", "quoting `style`: default, printf or shortest")
+		if fs.Parse(args[1:]) != nil {
+			return 2
+		}
+		s, ok := styles[*style]
+		if !ok {
+			fmt.Fprintf(stderr, "shellquote: unknown style %q\n", *style)
+			return 2
+		}
+		opts := &shellquote.QuoteOptions{Style: s}
+		if args[0] == "join" {
+			fmt.Fprintln(stdout, shellquote.JoinWithOptions(fs.Args(), opts))
+		} else {
+			for _, arg := range fs.Args() {
+				fmt.Fprintln(stdout, shellquote.QuoteWithOptions(arg, opts))
+			}
+		}
+		return 0
+	}
+	fmt.Fprint(stderr, usage)
+	return 2
+}
+
+// split splits the line read from stdin and prints its words.
+func split(stdin io.Reader, stdout, stderr io.Writer, dialect string, nul, asJSON bool) int {
+	opts, err := shellquote.Preset(dialect)
+	if err != nil {
+		fmt.Fprintf(stderr, "shellquote: %v\n", err)
+		return 2
+	}
+	input, err := io.ReadAll(stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "shellquote: %v\n", err)
+		return 1
+	}
+	words, err := shellquote.SplitWithOptions(strings.TrimSuffix(string(input), "\n"), opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "shellquote: %v\n", err)
+		return 1
+	}
+
+	switch {
+	case asJSON:
+		if words == nil {
+			words = []string{}
+		}
+		out, _ := json.Marshal(words)
+		fmt.Fprintf(stdout, "%s\n", out)
+	case nul:
+		for _, word := range words {
+			fmt.Fprintf(stdout, "%s\x00", word)
+		}
+	default:
+		for _, word := range words {
+			fmt.Fprintln(stdout, word)
+		}
+	}
+	return 0
+}
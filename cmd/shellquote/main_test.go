@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	for _, elem := range runTest {
+		var stdout, stderr bytes.Buffer
+		status := run(elem.args, strings.NewReader(elem.stdin), &stdout, &stderr)
+		if status != elem.status || stdout.String() != elem.stdout {
+			t.Errorf("Args %q, got status %d and output %q (%s), expected %d and %q", elem.args, status, stdout.String(), stderr.String(), elem.status, elem.stdout)
+		}
+	}
+}
+
+var runTest = []struct {
+	args   []string
+	stdin  string
+	status int
+	stdout string
+}{
+	{[]string{"split"}, "a 'b c' \"d\"\n", 0, "a\nb c\nd\n"},
+	{[]string{"split", "-0"}, "a 'b c'", 0, "a\x00b c\x00"},
+	{[]string{"split", "-json"}, "a 'b \"c'", 0, `["a","b \"c"]` + "\n"},
+	{[]string{"split", "-json"}, "", 0, "[]\n"},
+	{[]string{"split", "-dialect", "bash"}, "$'a\\tb'", 0, "a\tb\n"},
+	{[]string{"split", "--dialect=cmd"}, `copy "a b" c^ d`, 0, "copy\na b\nc d\n"},
+	{[]string{"split"}, "'unterminated", 1, ""},
+	{[]string{"split", "-dialect", "fish"}, "", 2, ""},
+	{[]string{"join", "a b", "it's", ""}, "", 0, "'a b' it\\'s ''\n"},
+	{[]string{"join", "-style", "printf", "a\tb"}, "", 0, "$'a\\tb'\n"},
+	{[]string{"quote", "a b", "c"}, "", 0, "'a b'\nc\n"},
+	{[]string{"quote", "-style", "bogus"}, "", 2, ""},
+	{[]string{"frobnicate"}, "", 2, ""},
+	{nil, "", 2, ""},
+}
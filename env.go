@@ -0,0 +1,152 @@
+package shellquote
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+var UnknownVariableError = errors.New("Unknown command variable")
+
+// QuoteEnv returns a KEY=value word for each variable in env, sorted by
+// key, with the value quoted so that the word is safe to use in env
+// invocations and export lines run by a shell. Values containing newlines
+// or other non-printable characters are written as $'...' strings to keep
+// each word on a single line, which requires a shell like bash to read
+// them back. A key that isn't a valid variable name is quoted together
+// with its value. The words are not meant for systemd's Environment=,
+// which treats % and backslashes differently.
+func QuoteEnv(env map[string]string) []string {
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	opts := &QuoteOptions{EscapeNonPrintable: true}
+	words := make([]string, len(keys))
+	var buf bytes.Buffer
+	for i, key := range keys {
+		buf.Reset()
+		if nameLen(key) == len(key) && len(key) > 0 {
+			quoteAssignment(key, env[key], &buf, opts)
+		} else {
+			quote(key+"="+env[key], &buf, opts)
+		}
+		words[i] = buf.String()
+	}
+	return words
+}
+
+// JoinEnv returns the words of QuoteEnv joined with a space.
+func JoinEnv(env map[string]string) string {
+	var buf bytes.Buffer
+	for i, word := range QuoteEnv(env) {
+		if i != 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(word)
+	}
+	return buf.String()
+}
+
+// envCommandSplitters maps the variables known to SplitEnvCommand to the
+// way their consumers split them.
+var envCommandSplitters = map[string]func(string) ([]string, error){
+	// git and most other programs run these with sh -c, appending their
+	// own arguments
+	"EDITOR":          Split,
+	"VISUAL":          Split,
+	"PAGER":           Split,
+	"GIT_EDITOR":      Split,
+	"GIT_PAGER":       Split,
+	"GIT_SSH_COMMAND": Split,
+	// sshd passes the command requested by the client to the user's shell
+	"SSH_ORIGINAL_COMMAND": Split,
+	// git runs GIT_SSH as a program without splitting it
+	"GIT_SSH": splitNothing,
+	// the go command splits GOFLAGS at whitespace without any quoting
+	"GOFLAGS": splitFields,
+	// the go command splits compilers and cgo flags with its own, simpler
+	// quoting rules
+	"CC":           splitGoQuoted,
+	"CXX":          splitGoQuoted,
+	"FC":           splitGoQuoted,
+	"CGO_CFLAGS":   splitGoQuoted,
+	"CGO_CPPFLAGS": splitGoQuoted,
+	"CGO_CXXFLAGS": splitGoQuoted,
+	"CGO_FFLAGS":   splitGoQuoted,
+	"CGO_LDFLAGS":  splitGoQuoted,
+}
+
+// SplitEnvCommand splits the value of the environment variable name, which
+// holds a command or a list of flags, the way the programs reading it do.
+// An unset or empty variable gives no words. The known variables are:
+//
+//   - EDITOR, VISUAL, PAGER, GIT_EDITOR, GIT_PAGER and GIT_SSH_COMMAND,
+//     which are run by sh and split like Split does. They may contain
+//     other shell syntax that Split passes through as words.
+//   - SSH_ORIGINAL_COMMAND, the command an ssh client asked a forced
+//     command to run, also split like Split. Its value is under the
+//     control of the client and must be validated before use.
+//   - GIT_SSH, a program git runs without splitting, which is returned as
+//     a single word.
+//   - GOFLAGS, which the go command splits at whitespace without any
+//     quoting.
+//   - CC, CXX, FC and the CGO_*FLAGS variables as read by the go command:
+//     they are split at whitespace, and a word starting with a single- or
+//     double-quote extends to the next such quote, without any escapes.
+//     Quotes elsewhere are literal.
+//
+// Any other name fails with UnknownVariableError.
+func SplitEnvCommand(name string) ([]string, error) {
+	split, ok := envCommandSplitters[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", UnknownVariableError, name)
+	}
+	value := os.Getenv(name)
+	if value == "" {
+		return nil, nil
+	}
+	return split(value)
+}
+
+func splitNothing(s string) ([]string, error) {
+	return []string{s}, nil
+}
+
+func splitFields(s string) ([]string, error) {
+	return strings.Fields(s), nil
+}
+
+// splitGoQuoted splits s like the go command's internal quoted.Split.
+func splitGoQuoted(s string) ([]string, error) {
+	var words []string
+	for {
+		s = strings.TrimLeft(s, " \t\n\r")
+		if s == "" {
+			return words, nil
+		}
+		if s[0] == '\'' || s[0] == '"' {
+			end := strings.IndexByte(s[1:], s[0])
+			if end < 0 {
+				if s[0] == '"' {
+					return nil, UnterminatedDoubleQuoteError
+				}
+				return nil, UnterminatedSingleQuoteError
+			}
+			words = append(words, s[1:end+1])
+			s = s[end+2:]
+			continue
+		}
+		end := strings.IndexAny(s, " \t\n\r")
+		if end < 0 {
+			end = len(s)
+		}
+		words = append(words, s[:end])
+		s = s[end:]
+	}
+}
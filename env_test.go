@@ -0,0 +1,65 @@
+package shellquote
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestQuoteEnv(t *testing.T) {
+	env := map[string]string{
+		"PATH":  "/usr/bin:~/bin",
+		"EMPTY": "",
+		"MSG":   "it's \"quoted\"",
+		"LINES": "a\nb",
+		"2BAD":  "x y",
+	}
+	output := QuoteEnv(env)
+	expected := []string{`'2BAD=x y'`, `EMPTY=`, `LINES=$'a\nb'`, `MSG='it'\''s "quoted"'`, `PATH='/usr/bin:~/bin'`}
+	if !reflect.DeepEqual(output, expected) {
+		t.Errorf("got %q, expected %q", output, expected)
+	}
+
+	split, _ := Preset("bash")
+	words, err := SplitWithOptions(JoinEnv(env), split)
+	if expected := []string{"2BAD=x y", "EMPTY=", "LINES=a\nb", "MSG=it's \"quoted\"", "PATH=/usr/bin:~/bin"}; err != nil || !reflect.DeepEqual(words, expected) {
+		t.Errorf("got %q (%v), expected %q", words, err, expected)
+	}
+	if output := JoinEnv(nil); output != "" {
+		t.Errorf("got %q for no variables", output)
+	}
+}
+
+func TestSplitEnvCommand(t *testing.T) {
+	for _, test := range splitEnvCommandTest {
+		t.Setenv(test.name, test.value)
+		output, err := SplitEnvCommand(test.name)
+		if !errors.Is(err, test.err) {
+			t.Errorf("Variable %s=%q, got error %v, expected %v", test.name, test.value, err, test.err)
+		} else if err == nil && !reflect.DeepEqual(output, test.expected) {
+			t.Errorf("Variable %s=%q, got %q, expected %q", test.name, test.value, output, test.expected)
+		}
+	}
+
+	if _, err := SplitEnvCommand("PATH"); !errors.Is(err, UnknownVariableError) {
+		t.Errorf("got error %v for PATH, expected %v", err, UnknownVariableError)
+	}
+}
+
+var splitEnvCommandTest = []struct {
+	name, value string
+	expected    []string
+	err         error
+}{
+	{"GIT_SSH_COMMAND", "", nil, nil},
+	{"GIT_SSH_COMMAND", `ssh -i "$HOME/my key" -o 'IdentitiesOnly yes'`, []string{"ssh", "-i", "$HOME/my key", "-o", "IdentitiesOnly yes"}, nil},
+	{"GIT_SSH_COMMAND", `ssh -i 'key`, nil, UnterminatedSingleQuoteError},
+	{"EDITOR", `code --wait`, []string{"code", "--wait"}, nil},
+	{"SSH_ORIGINAL_COMMAND", `git-upload-pack 'repo.git'`, []string{"git-upload-pack", "repo.git"}, nil},
+	{"GIT_SSH", `/opt/my ssh/ssh`, []string{"/opt/my ssh/ssh"}, nil},
+	{"GOFLAGS", " -mod=mod\t-tags='a b' ", []string{"-mod=mod", "-tags='a", "b'"}, nil},
+	{"CC", `"/opt/my cc/gcc" -m32 -I'x' 'a'b`, []string{"/opt/my cc/gcc", "-m32", "-I'x'", "a", "b"}, nil},
+	{"CGO_CFLAGS", `-I"a b" '-DX=\n'`, []string{`-I"a`, `b"`, `-DX=\n`}, nil},
+	{"CXX", `'clang++`, nil, UnterminatedSingleQuoteError},
+	{"CGO_LDFLAGS", `-L"/lib`, []string{`-L"/lib`}, nil},
+}
@@ -0,0 +1,180 @@
+package shellquote
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+var InvalidCodePointError = errors.New("Invalid Unicode code point")
+
+// InterpretEscapes decodes the backslash escapes understood by bash's
+// echo -e: \a, \b, \e, \E, \f, \n, \r, \t, \v and \\, \0 followed by up to
+// three octal digits, \x followed by up to two hex digits, and \u and \U
+// followed by up to four and eight hex digits giving a Unicode code point.
+// \c ends the output. Octal values are truncated to a byte, so \0NNN and
+// \xHH may produce invalid UTF-8.
+//
+// Any other backslash, including one followed by octal digits without a
+// leading zero, is kept as is. The only error is an escape for a code point
+// that cannot be encoded in UTF-8, which wraps InvalidCodePointError.
+func InterpretEscapes(s string) (string, error) {
+	return interpretEscapes(s, echoEscapes)
+}
+
+// InterpretPrintfB decodes backslash escapes the way printf's %b conversion
+// does in bash and dash. It differs from InterpretEscapes, which follows
+// echo -e, only in its handling of octal escapes: besides \0 followed by up
+// to three octal digits, %b also accepts \ followed by one to three octal
+// digits without the leading zero. So "\101" becomes "A" here, but stays
+// as is with InterpretEscapes, while "\0101" becomes "A" with both.
+func InterpretPrintfB(s string) (string, error) {
+	return interpretEscapes(s, printfEscapes)
+}
+
+// escapeMode selects the dialect of backslash escapes decodeEscape
+// understands.
+type escapeMode int
+
+const (
+	echoEscapes   escapeMode = iota // echo -e
+	printfEscapes                   // printf %b
+	ansiEscapes                     // $'...'
+)
+
+// interpretEscapes implements InterpretEscapes and InterpretPrintfB.
+func interpretEscapes(s string, mode escapeMode) (string, error) {
+	i := strings.IndexByte(s, '\\')
+	if i < 0 {
+		return s, nil
+	}
+	var buf strings.Builder
+	buf.Grow(len(s))
+	for ; i >= 0; i = strings.IndexByte(s, '\\') {
+		buf.WriteString(s[:i])
+		s = s[i:]
+		text, n, stop, err := decodeEscape(s, mode)
+		buf.WriteString(text)
+		if stop || err != nil {
+			return buf.String(), err
+		}
+		s = s[n:]
+	}
+	buf.WriteString(s)
+	return buf.String(), nil
+}
+
+// decodeEscape decodes the escape sequence at the start of s, which must
+// begin with a backslash, and returns its value and length. Unknown escapes
+// are returned as is. stop is set for the \c escape that ends echo and %b
+// output.
+func decodeEscape(s string, mode escapeMode) (text string, n int, stop bool, err error) {
+	if len(s) < 2 {
+		return s, len(s), false, nil
+	}
+	switch c := s[1]; c {
+	case 'a':
+		return "\a", 2, false, nil
+	case 'b':
+		return "\b", 2, false, nil
+	case 'e', 'E':
+		return "\x1b", 2, false, nil
+	case 'f':
+		return "\f", 2, false, nil
+	case 'n':
+		return "\n", 2, false, nil
+	case 'r':
+		return "\r", 2, false, nil
+	case 't':
+		return "\t", 2, false, nil
+	case 'v':
+		return "\v", 2, false, nil
+	case '\\':
+		return "\\", 2, false, nil
+	case '\'', '"', '?':
+		if mode == ansiEscapes {
+			return s[1:2], 2, false, nil
+		}
+	case 'c':
+		if mode != ansiEscapes {
+			return "", 2, true, nil
+		}
+		// \c at the end of the string, or followed by its closing quote,
+		// is kept as is
+		if len(s) == 2 || s[2] == '\'' {
+			return s[:2], 2, false, nil
+		}
+		// like bash, only the byte following \c is used, even if it starts
+		// a multi-byte character
+		n = 3
+		if strings.HasPrefix(s[2:], "\\\\") {
+			// bash lets \c\\ stand for control-backslash
+			n = 4
+		}
+		if s[2] == '?' {
+			return "\x7f", n, false, nil
+		}
+		return string([]byte{s[2] & 0x1f}), n, false, nil
+	case '0', '1', '2', '3', '4', '5', '6', '7':
+		var v rune
+		switch {
+		case mode == ansiEscapes:
+			v, n = parseDigits(s[1:], 8, 3)
+		case c == '0':
+			v, n = parseDigits(s[2:], 8, 3)
+			n++
+		case mode == printfEscapes:
+			v, n = parseDigits(s[1:], 8, 3)
+		default:
+			return s[:2], 2, false, nil
+		}
+		return string([]byte{byte(v)}), n + 1, false, nil
+	case 'x':
+		v, n := parseDigits(s[2:], 16, 2)
+		if n > 0 {
+			return string([]byte{byte(v)}), n + 2, false, nil
+		}
+	case 'u', 'U':
+		digits := 4
+		if c == 'U' {
+			digits = 8
+		}
+		v, n := parseDigits(s[2:], 16, digits)
+		if n == 0 {
+			break
+		}
+		if !utf8.ValidRune(v) {
+			if mode == ansiEscapes {
+				break
+			}
+			return "", n + 2, false, fmt.Errorf("%w: %s", InvalidCodePointError, s[:n+2])
+		}
+		return string(v), n + 2, false, nil
+	}
+	return s[:2], 2, false, nil
+}
+
+// parseDigits parses up to max digits in the given base at the start of s
+// and returns their value and how many there were.
+func parseDigits(s string, base, max int) (v rune, n int) {
+	for n < max && n < len(s) {
+		var d rune
+		switch c := rune(s[n]); {
+		case '0' <= c && c <= '9':
+			d = c - '0'
+		case 'a' <= c && c <= 'f':
+			d = c - 'a' + 10
+		case 'A' <= c && c <= 'F':
+			d = c - 'A' + 10
+		default:
+			return
+		}
+		if int(d) >= base {
+			return
+		}
+		v = v*rune(base) + d
+		n++
+	}
+	return
+}
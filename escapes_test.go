@@ -0,0 +1,86 @@
+package shellquote
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestInterpretEscapes(t *testing.T) {
+	for _, elem := range interpretEscapesTest {
+		output, err := InterpretEscapes(elem.input)
+		if err != nil {
+			t.Errorf("Input %q, got error %v", elem.input, err)
+		} else if output != elem.output {
+			t.Errorf("Input %q, got %q, expected %q", elem.input, output, elem.output)
+		}
+	}
+}
+
+func TestInterpretEscapesError(t *testing.T) {
+	for _, input := range []string{`a\ud800`, `a\U00110000`} {
+		if _, err := InterpretEscapes(input); !errors.Is(err, InvalidCodePointError) {
+			t.Errorf("Input %q, got error %v, expected InvalidCodePointError", input, err)
+		}
+	}
+}
+
+var interpretEscapesTest = []struct {
+	input  string
+	output string
+}{
+	{"plain", "plain"},
+	{`a\tb\nc\\d`, "a\tb\nc\\d"},
+	{`\a\b\e\E\f\r\v`, "\a\b\x1b\x1b\f\r\v"},
+	{`a\x41g \x414 \x4 \xZ \x`, "aAg A4 \x04 \\xZ \\x"},
+	{`a\0101b \01011 \0 \101`, "aAb A1 \x00 \\101"},
+	{`\u41 é \U0001F600 \uZ`, "A é \U0001F600 \\uZ"},
+	{`a\qb \' trailing\`, `a\qb \' trailing\`},
+	{`stop\chere`, "stop"},
+}
+
+func TestInterpretPrintfB(t *testing.T) {
+	for _, elem := range interpretPrintfBTest {
+		output, err := InterpretPrintfB(elem.input)
+		if err != nil {
+			t.Errorf("Input %q, got error %v", elem.input, err)
+		} else if output != elem.output {
+			t.Errorf("Input %q, got %q, expected %q", elem.input, output, elem.output)
+		}
+	}
+}
+
+func TestEscapesAgainstBash(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not found")
+	}
+	for _, elem := range interpretPrintfBTest {
+		for _, script := range []string{`echo -ne "$1"`, `printf %b "$1"`} {
+			cmd := exec.Command(bash, "-c", script, "bash", elem.input)
+			cmd.Env = append(os.Environ(), "LC_ALL=C.UTF-8")
+			want, err := cmd.Output()
+			if err != nil {
+				t.Fatal(err)
+			}
+			interpret := InterpretEscapes
+			if strings.HasPrefix(script, "printf") {
+				interpret = InterpretPrintfB
+			}
+			if got, _ := interpret(elem.input); got != string(want) {
+				t.Errorf("Input %q, %s gives %q, got %q", elem.input, script, want, got)
+			}
+		}
+	}
+}
+
+var interpretPrintfBTest = []struct {
+	input  string
+	output string
+}{
+	{`a\0101b \01011 \0`, "aAb A1 \x00"},
+	{`\101 \1011 \7 \08 \8`, "A A1 \a \x008 \\8"},
+	{`a\tb\x41é\c ignored`, "a\tbAé"},
+}
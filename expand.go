@@ -0,0 +1,409 @@
+package shellquote
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ExpandOptions configures ExpandAndSplit.
+type ExpandOptions struct {
+	// Lookup returns the value of the named variable and whether it is set.
+	// It is also asked for positional parameters like "1" and the special
+	// parameters ?, $, !, # and -. nil treats every parameter as unset.
+	Lookup func(name string) (string, bool)
+	// HomeDir returns the home directory of the named user, or of the
+	// current user if name is "". nil, or a false result, leaves the
+	// tilde-prefix as it is.
+	HomeDir func(name string) (string, bool)
+	// IFS holds the characters that unquoted expansion results are split
+	// at, like the shell variable of the same name. An empty IFS disables
+	// field splitting.
+	IFS string
+	// NoUnset makes expanding an unset parameter fail with
+	// UnsetParameterError, like set -u.
+	NoUnset bool
+}
+
+// DefaultExpandOptions returns options that look up variables in the
+// process environment and home directories in the user database, and split
+// at spaces, tabs and newlines.
+func DefaultExpandOptions() *ExpandOptions {
+	return &ExpandOptions{
+		Lookup:  os.LookupEnv,
+		HomeDir: lookupHomeDir,
+		IFS:     DefaultSplitChars,
+	}
+}
+
+func lookupHomeDir(name string) (string, bool) {
+	if name == "" {
+		home, err := os.UserHomeDir()
+		return home, err == nil
+	}
+	u, err := user.Lookup(name)
+	if err != nil {
+		return "", false
+	}
+	return u.HomeDir, true
+}
+
+// ExpandAndSplit turns input into words in the order a POSIX shell
+// processes the arguments of a simple command: tilde-prefixes and
+// parameters are expanded first, then the results of unquoted parameter
+// expansions are split into fields at the characters in IFS, and finally
+// quotes are removed. Unlike splitting a string whose variables were
+// replaced beforehand, a value like "x y" gives two words for $a but a
+// single one for "$a", and quotes inside values stay literal.
+//
+// Quoting follows POSIX sh, with $'...' strings decoded as in bash. Besides
+// $NAME and ${NAME}, the forms ${#NAME}, ${NAME-word}, ${NAME+word} and
+// ${NAME?word}, each also with a colon, are expanded; ${NAME?word} fails
+// with UnsetParameterError if NAME is unset. Command substitutions,
+// arithmetic, $@, $* and the other parameter operators fail with a
+// *SplitError wrapping an *ExpansionError, since they can't be evaluated
+// here. Pathname expansion is not done. opts nil means the default
+// options.
+//
+// Errors are returned as a *SplitError together with the words expanded
+// so far.
+func ExpandAndSplit(input string, opts *ExpandOptions) ([]string, error) {
+	if opts == nil {
+		opts = DefaultExpandOptions()
+	}
+	e := &expander{input: input, opts: opts, eval: true}
+	var words []string
+	for i := 0; i < len(input); {
+		if strings.IndexByte(DefaultSplitChars, input[i]) >= 0 {
+			i++
+			continue
+		}
+		pieces, next, err := e.word(i, i, 0, false)
+		if err != nil {
+			return words, err
+		}
+		words = e.fields(pieces, words)
+		i = next
+	}
+	return words, nil
+}
+
+// piece is a part of an expanded word.
+type piece struct {
+	text string
+	// split is set for the results of unquoted expansions, which are
+	// subject to field splitting.
+	split bool
+	// quoted is set for quoted text, which makes a word even if it is
+	// empty.
+	quoted bool
+}
+
+type expander struct {
+	input string
+	opts  *ExpandOptions
+	// eval is cleared while parsing the word of a parameter expansion
+	// that isn't used, which must not look up anything.
+	eval bool
+}
+
+// word expands the word starting at offset i. If stop is 0, the word is
+// unquoted and ends at a blank or the end of the input. Otherwise it ends
+// at stop, a double-quote or closing brace, which is consumed, and open is
+// the offset of the construct that stop closes. quoted reports whether the
+// word is inside double-quotes. word returns the word's pieces and the
+// offset just past it.
+func (e *expander) word(i, open int, stop byte, quoted bool) ([]piece, int, error) {
+	var pieces []piece
+	// literal text is subject to splitting only in the word of an unquoted
+	// parameter expansion
+	splitLiteral := stop == '}' && !quoted
+	if !quoted && i < len(e.input) && e.input[i] == '~' {
+		if p, next, ok := e.tilde(i, stop); ok {
+			pieces = append(pieces, p)
+			i = next
+		}
+	}
+
+	for i < len(e.input) {
+		c := e.input[i]
+		switch {
+		case stop == 0 && strings.IndexByte(DefaultSplitChars, c) >= 0:
+			return pieces, i, nil
+		case c == stop:
+			return pieces, i + 1, nil
+		case c == '\\':
+			if i+1 == len(e.input) {
+				if quoted {
+					pieces = append(pieces, piece{text: "\\", quoted: true})
+					i++
+					continue
+				}
+				return pieces, i, &SplitError{Kind: UnterminatedEscape, Err: UnterminatedEscapeError, Offset: i}
+			}
+			switch next := e.input[i+1]; {
+			case next == '\n':
+			case !quoted || strings.IndexByte("$`\"\\", next) >= 0 || next == stop:
+				pieces = append(pieces, piece{text: e.input[i+1 : i+2], quoted: true})
+			default:
+				pieces = append(pieces, piece{text: "\\", quoted: true})
+				i++
+				continue
+			}
+			i += 2
+		case c == '\'' && !quoted:
+			end := strings.IndexByte(e.input[i+1:], '\'')
+			if end < 0 {
+				return pieces, i, &SplitError{Kind: UnterminatedSingle, Err: UnterminatedSingleQuoteError, Offset: i}
+			}
+			pieces = append(pieces, piece{text: e.input[i+1 : i+1+end], quoted: true})
+			i += end + 2
+		case c == '"':
+			inner, next, err := e.word(i+1, i, '"', true)
+			pieces = append(append(pieces, piece{quoted: true}), inner...)
+			if err != nil {
+				return pieces, next, err
+			}
+			i = next
+		case c == '$':
+			expanded, next, err := e.dollar(i, quoted)
+			pieces = append(pieces, expanded...)
+			if err != nil {
+				return pieces, next, err
+			}
+			i = next
+		case c == '`':
+			return pieces, i, unsupportedExpansion("`", i)
+		default:
+			j := i + 1
+			for j < len(e.input) && strings.IndexByte("\\'\"$`", e.input[j]) < 0 && e.input[j] != stop &&
+				(stop != 0 || strings.IndexByte(DefaultSplitChars, e.input[j]) < 0) {
+				j++
+			}
+			pieces = append(pieces, piece{text: e.input[i:j], split: splitLiteral, quoted: quoted})
+			i = j
+		}
+	}
+
+	switch stop {
+	case '"':
+		return pieces, i, &SplitError{Kind: UnterminatedDouble, Err: UnterminatedDoubleQuoteError, Offset: open}
+	case '}':
+		return pieces, i, &SplitError{Kind: UnterminatedParameter, Err: UnterminatedParameterError, Offset: open}
+	}
+	return pieces, i, nil
+}
+
+// tilde expands the tilde-prefix at offset i of a word ending at stop. ok
+// is false if the prefix has quoted characters or no home directory is
+// known, leaving the tilde as it is.
+func (e *expander) tilde(i int, stop byte) (p piece, next int, ok bool) {
+	j := i + 1
+	for ; j < len(e.input) && e.input[j] != '/' && e.input[j] != stop; j++ {
+		c := e.input[j]
+		if stop == 0 && strings.IndexByte(DefaultSplitChars, c) >= 0 {
+			break
+		}
+		if strings.IndexByte("\\'\"$`", c) >= 0 {
+			return piece{}, i, false
+		}
+	}
+	if !e.eval || e.opts.HomeDir == nil {
+		return piece{}, i, false
+	}
+	home, ok := e.opts.HomeDir(e.input[i+1 : j])
+	if !ok {
+		return piece{}, i, false
+	}
+	return piece{text: home, quoted: true}, j, true
+}
+
+// dollar expands the construct starting with the $ at offset i.
+func (e *expander) dollar(i int, quoted bool) ([]piece, int, error) {
+	rest := e.input[i+1:]
+	switch {
+	case strings.HasPrefix(rest, "(("):
+		return nil, i, unsupportedExpansion("$((", i)
+	case strings.HasPrefix(rest, "("):
+		return nil, i, unsupportedExpansion("$(", i)
+	case strings.HasPrefix(rest, "{"):
+		return e.braced(i, quoted)
+	case strings.HasPrefix(rest, "'") && !quoted:
+		j := i + 2
+		for j < len(e.input) && e.input[j] != '\'' {
+			if e.input[j] == '\\' {
+				j++
+			}
+			j++
+		}
+		if j >= len(e.input) {
+			return nil, i, &SplitError{Kind: UnterminatedSingle, Err: UnterminatedSingleQuoteError, Offset: i}
+		}
+		text, _ := interpretEscapes(e.input[i+2:j], ansiEscapes)
+		return []piece{{text: text, quoted: true}}, j + 1, nil
+	case strings.HasPrefix(rest, "\"") && !quoted:
+		// a locale-specific string, translated to itself
+		return nil, i + 1, nil
+	case strings.HasPrefix(rest, "@") || strings.HasPrefix(rest, "*"):
+		return nil, i, unsupportedExpansion(e.input[i:i+2], i)
+	}
+
+	n := nameLen(rest)
+	if n == 0 && rest != "" && strings.IndexByte("0123456789?$!#-", rest[0]) >= 0 {
+		n = 1
+	}
+	if n == 0 {
+		return []piece{{text: "$", quoted: quoted}}, i + 1, nil
+	}
+	value, err := e.parameter(rest[:n], i)
+	return []piece{{text: value, split: !quoted, quoted: quoted}}, i + 1 + n, err
+}
+
+// braced expands the ${...} expansion starting at offset i.
+func (e *expander) braced(i int, quoted bool) ([]piece, int, error) {
+	j := i + 2
+	length := strings.HasPrefix(e.input[j:], "#") && !strings.HasPrefix(e.input[j:], "#}")
+	if length {
+		j++
+	}
+	n := parameterLen(e.input[j:])
+	if n == 0 {
+		if j == len(e.input) {
+			return nil, i, &SplitError{Kind: UnterminatedParameter, Err: UnterminatedParameterError, Offset: i}
+		}
+		return nil, i, unsupportedExpansion(e.input[i:j+1], i)
+	}
+	name := e.input[j : j+n]
+	j += n
+	if j == len(e.input) {
+		return nil, i, &SplitError{Kind: UnterminatedParameter, Err: UnterminatedParameterError, Offset: i}
+	}
+	if e.input[j] == '}' {
+		value, err := e.parameter(name, i)
+		if length {
+			value = strconv.Itoa(utf8.RuneCountInString(value))
+		}
+		return []piece{{text: value, split: !quoted, quoted: quoted}}, j + 1, err
+	}
+
+	colon := e.input[j] == ':'
+	k := j
+	if colon {
+		k++
+	}
+	if length || k == len(e.input) || strings.IndexByte("-+?", e.input[k]) < 0 {
+		return nil, i, unsupportedExpansion(e.input[i:min(k+1, len(e.input))], i)
+	}
+	op := e.input[k]
+	value, set := e.lookup(name)
+	null := !set || colon && value == ""
+
+	eval := e.eval
+	e.eval = eval && null != (op == '+')
+	word, next, err := e.word(k+1, i, '}', quoted)
+	e.eval = eval
+	if err != nil {
+		return nil, next, err
+	}
+	switch {
+	case op == '-' && null, op == '+' && !null:
+		return word, next, nil
+	case op == '+':
+		return nil, next, nil
+	case op == '?' && null && eval:
+		var message strings.Builder
+		for _, p := range word {
+			message.WriteString(p.text)
+		}
+		if message.Len() == 0 {
+			message.WriteString("parameter null or not set")
+		}
+		err := fmt.Errorf("%w: %s: %s", UnsetParameterError, name, message.String())
+		return nil, next, &SplitError{Kind: UnsetParameter, Err: err, Offset: i}
+	}
+	return []piece{{text: value, split: !quoted, quoted: quoted}}, next, nil
+}
+
+// parameterLen returns the length of the parameter name inside ${...} that
+// s starts with: a variable name, a positional parameter or a special
+// parameter other than @ and *.
+func parameterLen(s string) int {
+	if n := nameLen(s); n > 0 {
+		return n
+	}
+	n := 0
+	for n < len(s) && s[n] >= '0' && s[n] <= '9' {
+		n++
+	}
+	if n == 0 && s != "" && strings.IndexByte("?$!#-", s[0]) >= 0 {
+		n = 1
+	}
+	return n
+}
+
+func (e *expander) lookup(name string) (string, bool) {
+	if !e.eval || e.opts.Lookup == nil {
+		return "", false
+	}
+	return e.opts.Lookup(name)
+}
+
+// parameter returns the value of the parameter name, expanded at offset i.
+func (e *expander) parameter(name string, i int) (string, error) {
+	value, set := e.lookup(name)
+	if !set && e.eval && e.opts.NoUnset {
+		err := fmt.Errorf("%w: %s", UnsetParameterError, name)
+		return "", &SplitError{Kind: UnsetParameter, Err: err, Offset: i}
+	}
+	return value, nil
+}
+
+// fields splits the pieces of an expanded word at the IFS characters in
+// the split pieces and appends the fields to words. As in the shell, IFS
+// whitespace around a field is dropped, while each other IFS character
+// ends a field, even an empty one. A word without quotes or text gives no
+// field.
+func (e *expander) fields(pieces []piece, words []string) []string {
+	var buf strings.Builder
+	started, afterWhitespace := false, false
+	for _, p := range pieces {
+		if !p.split {
+			buf.WriteString(p.text)
+			if p.quoted || p.text != "" {
+				started, afterWhitespace = true, false
+			}
+			continue
+		}
+		for i := 0; i < len(p.text); i++ {
+			c := p.text[i]
+			switch {
+			case strings.IndexByte(e.opts.IFS, c) < 0:
+				buf.WriteByte(c)
+				started, afterWhitespace = true, false
+			case c == ' ' || c == '\t' || c == '\n':
+				if started {
+					words = append(words, buf.String())
+					buf.Reset()
+					started, afterWhitespace = false, true
+				}
+			default:
+				if started || !afterWhitespace {
+					words = append(words, buf.String())
+					buf.Reset()
+				}
+				started, afterWhitespace = false, false
+			}
+		}
+	}
+	if started {
+		words = append(words, buf.String())
+	}
+	return words
+}
+
+func unsupportedExpansion(construct string, offset int) *SplitError {
+	return &SplitError{Kind: UnsupportedExpansion, Err: &ExpansionError{Construct: construct, Offset: offset}, Offset: offset}
+}
@@ -0,0 +1,154 @@
+package shellquote
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var expandTestVars = map[string]string{
+	"A":    "x y",
+	"E":    "",
+	"C":    "a,b,,c",
+	"Q":    `"q w" 'e'`,
+	"S":    "  lead  trail  ",
+	"STAR": "*",
+	"1":    "one",
+	"#":    "1",
+}
+
+func expandTestOptions() *ExpandOptions {
+	return &ExpandOptions{
+		Lookup: func(name string) (string, bool) {
+			value, ok := expandTestVars[name]
+			return value, ok
+		},
+		HomeDir: func(name string) (string, bool) {
+			if name == "" {
+				return "/home/me", true
+			}
+			return "", false
+		},
+		IFS: DefaultSplitChars,
+	}
+}
+
+func TestExpandAndSplit(t *testing.T) {
+	for _, elem := range expandAndSplitTest {
+		opts := expandTestOptions()
+		if elem.ifs != "" {
+			opts.IFS = elem.ifs
+		}
+		output, err := ExpandAndSplit(elem.input, opts)
+		if err != nil {
+			t.Errorf("Input %q, got error %v", elem.input, err)
+		} else if !reflect.DeepEqual(output, elem.output) {
+			t.Errorf("Input %q, got %q, expected %q", elem.input, output, elem.output)
+		}
+	}
+}
+
+func TestExpandAndSplitBash(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not found")
+	}
+	env := []string{"HOME=/home/me"}
+	for name, value := range expandTestVars {
+		if nameLen(name) == len(name) {
+			env = append(env, name+"="+value)
+		}
+	}
+	for _, elem := range expandAndSplitTest[1:] {
+		script := "set -f; set -- one; printf '%s\\0' " + elem.input
+		if elem.ifs != "" {
+			script = "IFS=" + Join(elem.ifs) + "; " + script
+		}
+		cmd := exec.Command(bash, "-c", script)
+		cmd.Env = env
+		out, err := cmd.Output()
+		if err != nil {
+			t.Errorf("Input %q, bash failed: %v", elem.input, err)
+			continue
+		}
+		words := strings.Split(string(bytes.TrimSuffix(out, []byte{0})), "\x00")
+		if len(out) == 0 {
+			words = nil
+		}
+		if !reflect.DeepEqual(words, elem.output) {
+			t.Errorf("Input %q, bash gives %q, expected %q", elem.input, words, elem.output)
+		}
+	}
+}
+
+func TestExpandAndSplitError(t *testing.T) {
+	for _, elem := range expandAndSplitErrorTest {
+		opts := expandTestOptions()
+		opts.NoUnset = elem.noUnset
+		_, err := ExpandAndSplit(elem.input, opts)
+		var splitErr *SplitError
+		if !errors.As(err, &splitErr) || splitErr.Kind != elem.kind || splitErr.Offset != elem.offset {
+			t.Errorf("Input %q, got error %v, expected %v at %d", elem.input, err, elem.kind, elem.offset)
+		}
+	}
+
+	_, err := ExpandAndSplit("echo ${U?no user}", expandTestOptions())
+	if !errors.Is(err, UnsetParameterError) || !strings.Contains(err.Error(), "U: no user") {
+		t.Errorf("got error %v for ${U?no user}", err)
+	}
+}
+
+var expandAndSplitTest = []struct {
+	input  string
+	ifs    string
+	output []string
+}{
+	{"", "", nil},
+	{"echo $A", "", []string{"echo", "x", "y"}},
+	{`echo "$A"`, "", []string{"echo", "x y"}},
+	{`echo pre$A"post"`, "", []string{"echo", "prex", "ypost"}},
+	{`echo $E "$E" ''$E`, "", []string{"echo", "", ""}},
+	{`echo $Q`, "", []string{"echo", `"q`, `w"`, `'e'`}},
+	{`echo $S.`, "", []string{"echo", "lead", "trail", "."}},
+	{`echo $STAR '$A' \$A`, "", []string{"echo", "*", "$A", "$A"}},
+	{`echo ${A}z ${#A} ${#} $1 ${1}0 $10`, "", []string{"echo", "x", "yz", "3", "1", "one", "one0", "one0"}},
+	{`echo ${U:-"a b" c} ${E:-d} ${E-d} ${A+set} ${U+set}`, "", []string{"echo", "a b", "c", "d", "set"}},
+	{`echo "${U:-'a' "b c"}"`, "", []string{"echo", "'a' b c"}},
+	{`echo ${A:+$A} ${U:-${E:-nested}}`, "", []string{"echo", "x", "y", "nested"}},
+	{`echo ~ ~/src x~ "~" ~nobody-here/x`, "", []string{"echo", "/home/me", "/home/me/src", "x~", "~", "~nobody-here/x"}},
+	{`echo ${U:-~/a}`, "", []string{"echo", "/home/me/a"}},
+	{"echo a\\\nb \"c\\\nd\" \"\\a\\$\"", "", []string{"echo", "ab", "cd", `\a$`}},
+	{`echo $'a\tb' $"c d" $ "$"`, "", []string{"echo", "a\tb", "c d", "$", "$"}},
+	{`echo $C`, ",", []string{"echo", "a", "b", "", "c"}},
+	{`echo $C$A`, ", ", []string{"echo", "a", "b", "", "cx", "y"}},
+	{`echo $A`, ",", []string{"echo", "x y"}},
+	{`echo ${U:-a b}`, ",", []string{"echo", "a b"}},
+}
+
+var expandAndSplitErrorTest = []struct {
+	input   string
+	noUnset bool
+	kind    ErrKind
+	offset  int
+}{
+	{"echo $(ls)", false, UnsupportedExpansion, 5},
+	{"echo $((1+2))", false, UnsupportedExpansion, 5},
+	{"echo `ls`", false, UnsupportedExpansion, 5},
+	{`echo "$@"`, false, UnsupportedExpansion, 6},
+	{"echo ${A#x}", false, UnsupportedExpansion, 5},
+	{"echo ${A:=x}", false, UnsupportedExpansion, 5},
+	{"echo ${#A:-x}", false, UnsupportedExpansion, 5},
+	{"echo ${A", false, UnterminatedParameter, 5},
+	{"echo ${U:-x", false, UnterminatedParameter, 5},
+	{`echo "a`, false, UnterminatedDouble, 5},
+	{`echo ${U:-"}`, false, UnterminatedDouble, 10},
+	{"echo 'a", false, UnterminatedSingle, 5},
+	{"echo $'a\\'", false, UnterminatedSingle, 5},
+	{`echo a\`, false, UnterminatedEscape, 6},
+	{"echo $U", true, UnsetParameter, 5},
+	{"echo ${U:?}", false, UnsetParameter, 5},
+	{"echo ${E:?}", false, UnsetParameter, 5},
+}
@@ -0,0 +1,91 @@
+package shellquote
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// continuationIndent is written at the start of each continuation line.
+const continuationIndent = "  "
+
+// JoinWrapped quotes each word like Join and joins them into lines of at
+// most width columns, ending all but the last line in a backslash and
+// indenting the following ones. Lines are only broken between words, so a
+// word too long to fit gets a line of its own. A width of zero or less
+// disables wrapping.
+func JoinWrapped(words []string, width int) string {
+	var buf strings.Builder
+	col := 0
+	for i, word := range words {
+		quoted := Quote(word)
+		if i > 0 {
+			need := 1 + columns(quoted)
+			if i < len(words)-1 {
+				// leave room for the continuation
+				need += 2
+			}
+			if width > 0 && col+need > width {
+				buf.WriteString(" \\\n" + continuationIndent)
+				col = len(continuationIndent)
+			} else {
+				buf.WriteByte(' ')
+				col++
+			}
+		}
+		buf.WriteString(quoted)
+		col = advance(col, quoted)
+	}
+	return buf.String()
+}
+
+// JoinPretty quotes args like Join and lays them out over several lines in
+// the style of command examples in documentation: the program and any
+// leading words that aren't flags on the first line, then each flag on a
+// continuation line of its own, together with its value. A word is taken
+// to be the value of the flag before it unless it starts with a dash or the
+// flag already has a value as in --name=value. Other words, and any after
+// "--", get a line each.
+func JoinPretty(args []string) string {
+	var buf strings.Builder
+	i := 0
+	for ; i < len(args) && (i == 0 || !isFlag(args[i])); i++ {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(Quote(args[i]))
+	}
+	afterDashes := false
+	for ; i < len(args); i++ {
+		buf.WriteString(" \\\n" + continuationIndent)
+		buf.WriteString(Quote(args[i]))
+		if afterDashes || !isFlag(args[i]) {
+			continue
+		}
+		if args[i] == "--" {
+			afterDashes = true
+		} else if !strings.Contains(args[i], "=") && i+1 < len(args) && !isFlag(args[i+1]) {
+			i++
+			buf.WriteByte(' ')
+			buf.WriteString(Quote(args[i]))
+		}
+	}
+	return buf.String()
+}
+
+// isFlag reports whether word looks like a command line flag.
+func isFlag(word string) bool {
+	return len(word) > 1 && word[0] == '-'
+}
+
+// columns returns the number of columns s takes up.
+func columns(s string) int {
+	return utf8.RuneCountInString(s)
+}
+
+// advance returns the column reached by writing s at column col.
+func advance(col int, s string) int {
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		return columns(s[i+1:])
+	}
+	return col + columns(s)
+}
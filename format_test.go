@@ -0,0 +1,63 @@
+package shellquote
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestJoinWrapped(t *testing.T) {
+	for _, elem := range joinWrappedTest {
+		output := JoinWrapped(elem.input, elem.width)
+		if output != elem.output {
+			t.Errorf("Input %q, width %d, got %q, expected %q", elem.input, elem.width, output, elem.output)
+		}
+		for _, line := range strings.Split(output, "\n") {
+			word := strings.TrimSpace(strings.TrimSuffix(line, " \\"))
+			if elem.width > 0 && len(line) > elem.width && strings.Contains(word, " ") {
+				t.Errorf("Input %q, width %d, line %q is too long", elem.input, elem.width, line)
+			}
+		}
+		split, err := Split(output)
+		if err != nil || strings.Join(split, "\x00") != strings.Join(elem.input, "\x00") {
+			t.Errorf("Input %q, output %q splits into %q (%v)", elem.input, output, split, err)
+		}
+	}
+}
+
+func TestJoinPretty(t *testing.T) {
+	for _, elem := range joinPrettyTest {
+		output := JoinPretty(elem.input)
+		if output != elem.output {
+			t.Errorf("Input %q, got %q, expected %q", elem.input, output, elem.output)
+		}
+		split, err := Split(output)
+		if err != nil || strings.Join(split, "\x00") != strings.Join(elem.input, "\x00") {
+			t.Errorf("Input %q, output %q splits into %q (%v)", elem.input, output, split, err)
+		}
+	}
+}
+
+var joinWrappedTest = []struct {
+	input  []string
+	width  int
+	output string
+}{
+	{[]string{"echo", "a", "b"}, 0, "echo a b"},
+	{[]string{"echo", "a", "b"}, 8, "echo a b"},
+	{[]string{"echo", "a", "b"}, 7, "echo \\\n  a b"},
+	{[]string{"docker", "run", "--rm", "-v", "/a b:/c", "image"}, 20, "docker run --rm -v \\\n  '/a b:/c' image"},
+	{[]string{"ls", "a-very-long-file-name", "x"}, 10, "ls \\\n  a-very-long-file-name \\\n  x"},
+}
+
+var joinPrettyTest = []struct {
+	input  []string
+	output string
+}{
+	{[]string{}, ""},
+	{[]string{"ls"}, "ls"},
+	{[]string{"docker", "run", "--rm", "-v", "/a b:/c", "--name=x y", "image", "sh"},
+		"docker run \\\n  --rm \\\n  -v '/a b:/c' \\\n  '--name=x y' \\\n  image \\\n  sh"},
+	{[]string{"curl", "-H", "Accept: */*", "-X", "POST", "--", "-x", "url"},
+		"curl \\\n  -H 'Accept: */*' \\\n  -X POST \\\n  -- \\\n  -x \\\n  url"},
+	{[]string{"-", "a"}, "- a"},
+}
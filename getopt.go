@@ -0,0 +1,259 @@
+package shellquote
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	UnknownOptionError   = errors.New("Unknown option")
+	AmbiguousOptionError = errors.New("Ambiguous option")
+	MissingArgumentError = errors.New("Option requires an argument")
+	UnexpectedValueError = errors.New("Option doesn't take an argument")
+)
+
+// OptSpec describes the options a program accepts, for ParseArgs.
+type OptSpec struct {
+	// Short lists the single letter options in getopt's syntax: a letter
+	// followed by a colon takes an argument, as in "vo:".
+	Short string
+	// Long lists the names of the long options. A name followed by = takes
+	// an argument, as in "output=".
+	Long []string
+	// Permute lets options follow operands, as GNU getopt does by default.
+	// Otherwise the first operand ends the options, as POSIX requires.
+	Permute bool
+}
+
+// Opt is an option found by ParseArgs.
+type Opt struct {
+	// Name is the option letter, or the full name of a long option even if
+	// it was abbreviated.
+	Name string
+	Long bool
+	// Value is the argument of the option, if it takes one.
+	Value string
+	// Index is the index of the argument the option was found in.
+	Index int
+}
+
+// OptionError is returned by ParseArgs for an option it can't parse.
+type OptionError struct {
+	// Option is the option as written, like -x or --name.
+	Option string
+	// Index is the index of the argument holding it.
+	Index int
+	Err   error
+}
+
+func (e *OptionError) Error() string {
+	return e.Err.Error() + " " + e.Option
+}
+
+func (e *OptionError) Unwrap() error {
+	return e.Err
+}
+
+// ParseArgs parses the options in args, which must not include the program
+// name, according to spec, and returns them along with the operands.
+//
+// Short options may be grouped as in -vx, and take their argument either
+// from the rest of the word, as in -ovalue, or from the next one. Long
+// options start with -- and take their argument as in --name=value or
+// --name value; they may be abbreviated to any unique prefix. A -- word
+// ends the options and isn't returned, while a lone - is an operand.
+//
+// Parsing stops at the first error, which is an *OptionError wrapping
+// UnknownOptionError, AmbiguousOptionError, MissingArgumentError or
+// UnexpectedValueError.
+func ParseArgs(args []string, spec *OptSpec) (opts []Opt, operands []string, err error) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return opts, append(operands, args[i+1:]...), nil
+		case strings.HasPrefix(arg, "--"):
+			opt, consumed, err := parseLong(args, i, spec)
+			if err != nil {
+				return opts, operands, err
+			}
+			opts = append(opts, opt)
+			i += consumed
+		case len(arg) > 1 && arg[0] == '-':
+			found, consumed, err := parseShort(args, i, spec)
+			opts = append(opts, found...)
+			if err != nil {
+				return opts, operands, err
+			}
+			i += consumed
+		case spec.Permute:
+			operands = append(operands, arg)
+		default:
+			return opts, append(operands, args[i:]...), nil
+		}
+	}
+	return opts, operands, nil
+}
+
+// parseLong parses the long option in args[i] and returns it along with
+// the number of following arguments it consumed.
+func parseLong(args []string, i int, spec *OptSpec) (Opt, int, error) {
+	name, value, hasValue := strings.Cut(args[i][2:], "=")
+	option := "--" + name
+	match, err := matchLong(spec, name)
+	if err != nil {
+		return Opt{}, 0, &OptionError{Option: option, Index: i, Err: err}
+	}
+
+	opt := Opt{Name: strings.TrimSuffix(match, "="), Long: true, Index: i}
+	takesValue := strings.HasSuffix(match, "=")
+	switch {
+	case !takesValue && hasValue:
+		return Opt{}, 0, &OptionError{Option: option, Index: i, Err: UnexpectedValueError}
+	case !takesValue:
+		return opt, 0, nil
+	case hasValue:
+		opt.Value = value
+		return opt, 0, nil
+	case i+1 < len(args):
+		opt.Value = args[i+1]
+		return opt, 1, nil
+	}
+	return Opt{}, 0, &OptionError{Option: option, Index: i, Err: MissingArgumentError}
+}
+
+// matchLong returns the entry of spec.Long matching the given name or a
+// unique abbreviation of it. An empty name matches nothing.
+func matchLong(spec *OptSpec, name string) (string, error) {
+	if name == "" {
+		return "", UnknownOptionError
+	}
+	match, ambiguous := "", false
+	for _, long := range spec.Long {
+		candidate := strings.TrimSuffix(long, "=")
+		if candidate == name {
+			return long, nil
+		} else if strings.HasPrefix(candidate, name) {
+			ambiguous = match != ""
+			match = long
+		}
+	}
+	switch {
+	case match == "":
+		return "", UnknownOptionError
+	case ambiguous:
+		return "", AmbiguousOptionError
+	}
+	return match, nil
+}
+
+// parseShort parses the group of short options in args[i] and returns them
+// along with the number of following arguments consumed.
+func parseShort(args []string, i int, spec *OptSpec) ([]Opt, int, error) {
+	var opts []Opt
+	group := args[i][1:]
+	for j := 0; j < len(group); j++ {
+		c := group[j]
+		k := strings.IndexByte(spec.Short, c)
+		if c == ':' || k < 0 {
+			return opts, 0, &OptionError{Option: "-" + group[j:j+1], Index: i, Err: UnknownOptionError}
+		}
+		opt := Opt{Name: group[j : j+1], Index: i}
+		if k+1 == len(spec.Short) || spec.Short[k+1] != ':' {
+			opts = append(opts, opt)
+			continue
+		}
+		switch {
+		case j+1 < len(group):
+			opt.Value = group[j+1:]
+			return append(opts, opt), 0, nil
+		case i+1 < len(args):
+			opt.Value = args[i+1]
+			return append(opts, opt), 1, nil
+		}
+		return opts, 0, &OptionError{Option: "-" + opt.Name, Index: i, Err: MissingArgumentError}
+	}
+	return opts, 0, nil
+}
+
+// LongOptionForm selects how NormalizeLongOptions writes the argument of a
+// long option.
+type LongOptionForm int
+
+const (
+	// JoinedLongOptions writes --name=value.
+	JoinedLongOptions LongOptionForm = iota
+	// SeparateLongOptions writes --name value.
+	SeparateLongOptions
+)
+
+// NormalizeLongOptions splits the command in input according to opts and
+// rewrites the long options taking an argument, as listed in spec, to the
+// given form. The first word is taken to be the program name. Options are
+// found like ParseArgs does, up to the first -- or, unless spec.Permute is
+// set, the first operand. Abbreviated long options are rewritten too, but
+// keep their name as written. An unknown or ambiguous long option makes
+// NormalizeLongOptions return an *OptionError and input unchanged.
+//
+// Only the rewritten options change; everything else, including quoting
+// and whitespace, is kept as it was. The words of a rewritten option are
+// quoted again like Quote does.
+func NormalizeLongOptions(input string, spec *OptSpec, form LongOptionForm, opts *SplitOptions) (string, error) {
+	tokens, err := SplitTokens(input, opts)
+	if err != nil {
+		return input, err
+	}
+	args := make([]string, len(tokens))
+	for i, tok := range tokens {
+		args[i] = tok.Value
+	}
+
+	var buf strings.Builder
+	last := 0
+	replace := func(start, end int, text string) {
+		buf.WriteString(input[last:start])
+		buf.WriteString(text)
+		last = end
+	}
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			i = len(args)
+		case strings.HasPrefix(arg, "--"):
+			name, value, joined := strings.Cut(arg[2:], "=")
+			match, err := matchLong(spec, name)
+			if err != nil {
+				return input, &OptionError{Option: "--" + name, Index: i, Err: err}
+			}
+			if !strings.HasSuffix(match, "=") {
+				continue
+			}
+			switch {
+			case joined && form == SeparateLongOptions:
+				replace(tokens[i].Start, tokens[i].End, Quote("--"+name)+" "+Quote(value))
+			case !joined && form == JoinedLongOptions && i+1 < len(args):
+				replace(tokens[i].Start, tokens[i+1].End, Quote("--"+name)+"="+quoteValue(args[i+1]))
+				i++
+			case !joined:
+				i++
+			}
+		case len(arg) > 1 && arg[0] == '-':
+			_, consumed, _ := parseShort(args, i, spec)
+			i += consumed
+		case !spec.Permute:
+			i = len(args)
+		}
+	}
+	buf.WriteString(input[last:])
+	return buf.String(), nil
+}
+
+// quoteValue quotes the value following = in a word, where it needs no
+// quotes if empty.
+func quoteValue(value string) string {
+	if value == "" {
+		return ""
+	}
+	return Quote(value)
+}
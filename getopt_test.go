@@ -0,0 +1,110 @@
+package shellquote
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseArgs(t *testing.T) {
+	spec := &OptSpec{Short: "vxo:", Long: []string{"verbose", "output=", "out-dir=", "dry-run"}}
+	for _, elem := range parseArgsTest {
+		spec.Permute = elem.permute
+		opts, operands, err := ParseArgs(elem.input, spec)
+		if err != nil {
+			t.Errorf("Input %q, got error %v", elem.input, err)
+		} else if !reflect.DeepEqual(opts, elem.opts) || !reflect.DeepEqual(operands, elem.operands) {
+			t.Errorf("Input %q, got %+v %q, expected %+v %q", elem.input, opts, operands, elem.opts, elem.operands)
+		}
+	}
+}
+
+func TestParseArgsError(t *testing.T) {
+	spec := &OptSpec{Short: "vo:", Long: []string{"verbose", "output=", "out-dir="}}
+	for _, elem := range parseArgsErrorTest {
+		_, _, err := ParseArgs(elem.input, spec)
+		var optErr *OptionError
+		if !errors.As(err, &optErr) || !errors.Is(err, elem.err) || optErr.Option != elem.option || optErr.Index != elem.index {
+			t.Errorf("Input %q, got error %v, expected %v for %s at %d", elem.input, err, elem.err, elem.option, elem.index)
+		}
+	}
+}
+
+func TestNormalizeLongOptions(t *testing.T) {
+	spec := &OptSpec{Short: "vo:", Long: []string{"verbose", "output=", "name="}}
+	for _, elem := range normalizeLongOptionsTest {
+		output, err := NormalizeLongOptions(elem.input, spec, elem.form, nil)
+		if err != nil {
+			t.Errorf("Input %q, got error %v", elem.input, err)
+		} else if output != elem.output {
+			t.Errorf("Input %q, got %q, expected %q", elem.input, output, elem.output)
+		}
+	}
+}
+
+func TestNormalizeLongOptionsError(t *testing.T) {
+	spec := &OptSpec{Long: []string{"output=", "out-dir="}}
+	for input, expected := range map[string]error{
+		"cmd --bogus x": UnknownOptionError,
+		"cmd --ou x":    AmbiguousOptionError,
+	} {
+		output, err := NormalizeLongOptions(input, spec, JoinedLongOptions, nil)
+		var optErr *OptionError
+		if !errors.As(err, &optErr) || !errors.Is(err, expected) || optErr.Index != 1 || output != input {
+			t.Errorf("Input %q, got %q and error %v, expected %v", input, output, err, expected)
+		}
+	}
+}
+
+var parseArgsTest = []struct {
+	input    []string
+	permute  bool
+	opts     []Opt
+	operands []string
+}{
+	{[]string{}, false, nil, nil},
+	{[]string{"-vx", "-ofile", "a", "-v"}, false,
+		[]Opt{{Name: "v"}, {Name: "x"}, {Name: "o", Value: "file", Index: 1}},
+		[]string{"a", "-v"}},
+	{[]string{"-vo", "file", "a", "-x", "-", "--", "-v"}, true,
+		[]Opt{{Name: "v"}, {Name: "o", Value: "file"}, {Name: "x", Index: 3}},
+		[]string{"a", "-", "-v"}},
+	{[]string{"--verb", "--output=a=b", "--output", "c", "--dry", "--out-dir", ""}, false,
+		[]Opt{{Name: "verbose", Long: true}, {Name: "output", Long: true, Value: "a=b", Index: 1},
+			{Name: "output", Long: true, Value: "c", Index: 2}, {Name: "dry-run", Long: true, Index: 4},
+			{Name: "out-dir", Long: true, Index: 5}},
+		nil},
+}
+
+var parseArgsErrorTest = []struct {
+	input  []string
+	err    error
+	option string
+	index  int
+}{
+	{[]string{"-v", "-q"}, UnknownOptionError, "-q", 1},
+	{[]string{"-v:"}, UnknownOptionError, "-:", 0},
+	{[]string{"-vo"}, MissingArgumentError, "-o", 0},
+	{[]string{"--out"}, AmbiguousOptionError, "--out", 0},
+	{[]string{"--outp"}, MissingArgumentError, "--outp", 0},
+	{[]string{"--verbose=1"}, UnexpectedValueError, "--verbose", 0},
+	{[]string{"--quiet"}, UnknownOptionError, "--quiet", 0},
+	{[]string{"-v", "--=x"}, UnknownOptionError, "--", 1},
+}
+
+var normalizeLongOptionsTest = []struct {
+	input  string
+	form   LongOptionForm
+	output string
+}{
+	{"cmd  --output 'a b'   --verbose x", JoinedLongOptions, "cmd  --output='a b'   --verbose x"},
+	{"cmd --output=\"a b\" --name=  --verbose", SeparateLongOptions, "cmd --output 'a b' --name ''  --verbose"},
+	{"cmd --name ''", JoinedLongOptions, "cmd --name="},
+	{`cmd "--output=it's" --outp x`, SeparateLongOptions, `cmd --output it\'s --outp x`},
+	{"cmd -o --output --output x", JoinedLongOptions, "cmd -o --output --output=x"},
+	{"cmd -v --name a b --name c -- --name d", JoinedLongOptions, "cmd -v --name=a b --name c -- --name d"},
+	{"cmd --name=~/x", SeparateLongOptions, `cmd --name \~/x`},
+	{"cmd --output", JoinedLongOptions, "cmd --output"},
+	{"cmd --outp x --verb y", JoinedLongOptions, "cmd --outp=x --verb y"},
+	{"cmd --na=x --out y", SeparateLongOptions, "cmd --na x --out y"},
+}
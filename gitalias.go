@@ -0,0 +1,174 @@
+package shellquote
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var InvalidGitValueError = errors.New("Invalid git config value")
+
+// ParseGitAlias parses the value of a git alias as it appears in a git
+// config file, after the =. The value is first decoded following git's
+// config syntax, see UnquoteGitValue. Aliases starting with ! are shell
+// aliases, which git runs with sh; their command is split with the default
+// split options and shell is set. Other aliases are split the way git
+// does, which is like sh except that inside double-quotes a backslash
+// escapes any character.
+func ParseGitAlias(value string) (args []string, shell bool, err error) {
+	alias, err := UnquoteGitValue(value)
+	if err != nil {
+		return nil, false, err
+	}
+	if strings.HasPrefix(alias, "!") {
+		args, err = Split(alias[1:])
+		return args, true, err
+	}
+	args, err = splitGitCmdline(alias)
+	return args, false, err
+}
+
+// JoinGitAlias returns a value for a git config file that defines an alias
+// running args, either as a git command or, if shell is set, as a shell
+// alias starting with !.
+func JoinGitAlias(args []string, shell bool) string {
+	alias := Join(args...)
+	if shell {
+		alias = "!" + alias
+	}
+	return QuoteGitValue(alias)
+}
+
+// UnquoteGitValue decodes a value from a git config file, which may be
+// followed by a comment. Outside of double-quotes, leading and trailing
+// whitespace is dropped, every other space or tab becomes a space, and # or
+// ; starts a comment. The escapes \", \\, \n, \t and \b are decoded
+// everywhere, and a backslash before a newline continues the value on the
+// next line. Any other escape, a newline inside double-quotes or a missing
+// closing quote is an error wrapping InvalidGitValueError.
+func UnquoteGitValue(value string) (string, error) {
+	var buf strings.Builder
+	quoted := false
+	spaces := 0
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c == '\n' {
+			if quoted {
+				return "", fmt.Errorf("%w: newline in quoted string at offset %d", InvalidGitValueError, i)
+			}
+			break
+		}
+		if !quoted && (c == ' ' || c == '\t' || c == '\r') {
+			if buf.Len() > 0 {
+				spaces++
+			}
+			continue
+		}
+		if !quoted && (c == '#' || c == ';') {
+			break
+		}
+		for ; spaces > 0; spaces-- {
+			buf.WriteByte(' ')
+		}
+		switch c {
+		case '"':
+			quoted = !quoted
+		case '\\':
+			i++
+			if i == len(value) {
+				return "", fmt.Errorf("%w: trailing backslash", InvalidGitValueError)
+			}
+			switch value[i] {
+			case '\n':
+			case 't':
+				buf.WriteByte('\t')
+			case 'b':
+				buf.WriteByte('\b')
+			case 'n':
+				buf.WriteByte('\n')
+			case '\\', '"':
+				buf.WriteByte(value[i])
+			default:
+				return "", fmt.Errorf("%w: unknown escape at offset %d", InvalidGitValueError, i-1)
+			}
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	if quoted {
+		return "", fmt.Errorf("%w: missing closing quote", InvalidGitValueError)
+	}
+	return buf.String(), nil
+}
+
+// QuoteGitValue encodes s for a git config file the way git config does
+// itself: backslashes, double-quotes, newlines and tabs are escaped, and
+// the value is put in double-quotes if it starts or ends with a space or
+// contains # or ;.
+func QuoteGitValue(s string) string {
+	var buf strings.Builder
+	quote := strings.HasPrefix(s, " ") || strings.HasSuffix(s, " ") || strings.ContainsAny(s, "#;")
+	if quote {
+		buf.WriteByte('"')
+	}
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '"', '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	if quote {
+		buf.WriteByte('"')
+	}
+	return buf.String()
+}
+
+// splitGitCmdline splits a git alias like git's split_cmdline.
+func splitGitCmdline(s string) ([]string, error) {
+	var args []string
+	var buf strings.Builder
+	var quote byte
+	inWord := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote == 0 && strings.IndexByte(" \t\n\v\f\r", c) >= 0:
+			if inWord {
+				args = append(args, buf.String())
+				buf.Reset()
+				inWord = false
+			}
+			continue
+		case quote == 0 && (c == '\'' || c == '"'):
+			quote = c
+		case c == quote:
+			quote = 0
+		case c == '\\' && quote != '\'':
+			i++
+			if i == len(s) {
+				return nil, UnterminatedEscapeError
+			}
+			buf.WriteByte(s[i])
+		default:
+			buf.WriteByte(c)
+		}
+		inWord = true
+	}
+	switch quote {
+	case '\'':
+		return nil, UnterminatedSingleQuoteError
+	case '"':
+		return nil, UnterminatedDoubleQuoteError
+	}
+	if inWord {
+		args = append(args, buf.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,114 @@
+package shellquote
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnquoteGitValue(t *testing.T) {
+	for _, elem := range unquoteGitValueTest {
+		output, err := UnquoteGitValue(elem.input)
+		if err != nil {
+			t.Errorf("Input %q, got error %v", elem.input, err)
+		} else if output != elem.output {
+			t.Errorf("Input %q, got %q, expected %q", elem.input, output, elem.output)
+		}
+	}
+	for _, input := range []string{`"open`, `a\x`, "\"a\nb\"", `a\`} {
+		if _, err := UnquoteGitValue(input); !errors.Is(err, InvalidGitValueError) {
+			t.Errorf("Input %q, got error %v, expected InvalidGitValueError", input, err)
+		}
+	}
+}
+
+func TestGitValueAgainstGit(t *testing.T) {
+	git, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git not found")
+	}
+	file := filepath.Join(t.TempDir(), "config")
+	var config strings.Builder
+	config.WriteString("[test]\n")
+	for _, elem := range unquoteGitValueTest {
+		config.WriteString("\tvalue = " + elem.input + "\n")
+	}
+	for _, elem := range quoteGitValueTest {
+		config.WriteString("\tvalue = " + QuoteGitValue(elem) + "\n")
+	}
+	if err := os.WriteFile(file, []byte(config.String()), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command(git, "config", "-f", file, "-z", "--get-all", "test.value").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	var expected []string
+	for _, elem := range unquoteGitValueTest {
+		expected = append(expected, elem.output)
+	}
+	expected = append(expected, quoteGitValueTest...)
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("git gives %q, expected %q", values, expected)
+	}
+}
+
+func TestParseGitAlias(t *testing.T) {
+	for _, elem := range parseGitAliasTest {
+		args, shell, err := ParseGitAlias(elem.input)
+		if err != nil {
+			t.Errorf("Input %q, got error %v", elem.input, err)
+		} else if !reflect.DeepEqual(args, elem.args) || shell != elem.shell {
+			t.Errorf("Input %q, got %q (shell %v), expected %q (shell %v)", elem.input, args, shell, elem.args, elem.shell)
+		}
+		if joined := JoinGitAlias(elem.args, elem.shell); !reflect.DeepEqual(mustParseGitAlias(t, joined), elem.args) {
+			t.Errorf("Args %q, joined as %q which doesn't parse back", elem.args, joined)
+		}
+	}
+}
+
+func mustParseGitAlias(t *testing.T, value string) []string {
+	args, _, err := ParseGitAlias(value)
+	if err != nil {
+		t.Errorf("Input %q, got error %v", value, err)
+	}
+	return args
+}
+
+var unquoteGitValueTest = []struct {
+	input  string
+	output string
+}{
+	{"plain", "plain"},
+	{"  a \t b  ", "a   b"},
+	{`"  spaced ; x  " # comment`, "  spaced ; x  "},
+	{`log --format=\"%h\t%s\" ; c`, "log --format=\"%h\t%s\""},
+	{`a"b"c\\d\n`, "a" + "bc\\d\n"},
+	{`!f() { echo \"$1\"; }; f`, `!f() { echo "$1"`},
+	{`"!f() { echo \"$1\"; }; f"`, `!f() { echo "$1"; }; f`},
+}
+
+var quoteGitValueTest = []string{
+	"",
+	"plain value",
+	" lead",
+	`!f() { echo "$1"; }; f`,
+	"a\tb\\c\nd #x",
+}
+
+var parseGitAliasTest = []struct {
+	input string
+	args  []string
+	shell bool
+}{
+	{"log --oneline -5", []string{"log", "--oneline", "-5"}, false},
+	{`log \"--format=%h %s\" 'a\\b'`, []string{"log", "--format=%h %s", `a\b`}, false},
+	{`commit -m \"say \\\"hi\\\"\"`, []string{"commit", "-m", `say "hi"`}, false},
+	{`"!git log | head -n 1"`, []string{"git", "log", "|", "head", "-n", "1"}, true},
+	{`!echo 'a b' \"c\"`, []string{"echo", "a b", "c"}, true},
+}
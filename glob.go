@@ -0,0 +1,28 @@
+package shellquote
+
+import "strings"
+
+// globChars are the characters with a special meaning in glob patterns,
+// including the backslash used to escape them.
+const globChars = "*?[]\\"
+
+// GlobQuote escapes the glob metacharacters *, ?, [ and ] in s with a
+// backslash, so that s matches only itself when used as a pattern by
+// fnmatch and the tools built on it, like rsync filters, find -name or git
+// pathspecs. Backslashes are escaped as well, since they would otherwise
+// escape the following character. Everything else is left untouched; the
+// result is not quoted for the shell.
+func GlobQuote(s string) string {
+	if strings.IndexAny(s, globChars) < 0 {
+		return s
+	}
+	var buf strings.Builder
+	buf.Grow(len(s) + 4)
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(globChars, s[i]) >= 0 {
+			buf.WriteByte('\\')
+		}
+		buf.WriteByte(s[i])
+	}
+	return buf.String()
+}
@@ -0,0 +1,31 @@
+package shellquote
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestGlobQuote(t *testing.T) {
+	for _, elem := range globQuoteTest {
+		output := GlobQuote(elem.input)
+		if output != elem.output {
+			t.Errorf("Input %q, got %q, expected %q", elem.input, output, elem.output)
+		}
+		if matched, err := filepath.Match(output, elem.input); err != nil || !matched {
+			t.Errorf("Input %q, pattern %q does not match it (%v)", elem.input, output, err)
+		}
+	}
+}
+
+var globQuoteTest = []struct {
+	input  string
+	output string
+}{
+	{"", ""},
+	{"plain file.txt", "plain file.txt"},
+	{"*.go", `\*.go`},
+	{"a?b[0-9]c]", `a\?b\[0-9\]c\]`},
+	{`dir\*`, `dir\\\*`},
+	{"it's {a,b} $x ~", "it's {a,b} $x ~"},
+	{"café*", "café\\*"},
+}
@@ -0,0 +1,175 @@
+package shellquote
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HistoryExpansions returns the offsets of the ! characters in input that
+// would start a history expansion if the input was typed into an
+// interactive bash session. A ! is left alone by bash if it is escaped,
+// inside single-quotes, followed by whitespace or =, at the end of the input
+// or directly before the closing quote of a double-quoted string.
+//
+// Use QuoteOptions.EscapeHistory to produce output without any such !.
+func HistoryExpansions(input string) []int {
+	var found []int
+	inDouble := false
+	for i := 0; i < len(input); i++ {
+		switch input[i] {
+		case '\\':
+			i++
+		case '\'':
+			if !inDouble {
+				if end := strings.IndexByte(input[i+1:], '\''); end >= 0 {
+					i += end + 1
+				} else {
+					i = len(input)
+				}
+			}
+		case '"':
+			inDouble = !inDouble
+		case '!':
+			if i+1 == len(input) || strings.IndexByte(" \t\r\n=", input[i+1]) >= 0 {
+				continue
+			}
+			if inDouble && input[i+1] == '"' {
+				continue
+			}
+			found = append(found, i)
+		}
+	}
+	return found
+}
+
+// HistoryEntry is a command read from a bash history file by ParseHistory.
+type HistoryEntry struct {
+	// Time is when the command was run, or the zero time if the file has
+	// no timestamp for it.
+	Time time.Time
+	// Command is the command as written to the file. The lines of a
+	// multi-line command are separated by newlines.
+	Command string
+	// Args is Command split with bash's quoting rules. Comments are
+	// dropped, while operators like | and ; are kept as words of their
+	// own if surrounded by whitespace. Command substitutions are kept
+	// together with the word they appear in.
+	Args []string
+	// Err is the error splitting Command, if any. Args then holds the words
+	// found despite the error.
+	Err error
+	// Line is the 1-based number of the line the command starts on.
+	Line int
+}
+
+// historyContinuationLines is the number of lines ParseHistory looks ahead
+// for the end of a command that doesn't end on its first line.
+const historyContinuationLines = 20
+
+// ParseHistory reads a bash history file from r and returns its entries.
+// Lines consisting of # followed by digits are the timestamps bash writes
+// when HISTTIMEFORMAT is set, and apply to the following entry. In a file
+// with timestamps, all lines up to the next timestamp belong to the same
+// entry, as bash saves multi-line commands that way when the lithist
+// option is set. Without timestamps, a line that ends inside a quoted
+// string or in a backslash is joined with the following lines if one of
+// the next 20 completes the command. Otherwise, as for a stray quote in
+// echo don't, it stays an entry of its own.
+//
+// Commands that can't be split are returned with Err set; the error
+// returned by ParseHistory is only ever an error reading from r, in which
+// case the entries of the lines read before are returned with it.
+func ParseHistory(r io.Reader) ([]HistoryEntry, error) {
+	opts := bashSplitOptions()
+	opts.CommentChar = '#'
+	opts.Substitutions = true
+	opts.CollectErrors = true
+
+	var lines []string
+	var readErr error
+	br := bufio.NewReader(r)
+	for readErr == nil {
+		var text string
+		text, readErr = br.ReadString('\n')
+		if len(text) > 0 {
+			lines = append(lines, strings.TrimSuffix(text, "\n"))
+		}
+	}
+	if readErr == io.EOF {
+		readErr = nil
+	}
+
+	var entries []HistoryEntry
+	var stamp time.Time
+	timestamps, pending := false, false
+	// resplit is set while the last entry has lines joined to it that
+	// haven't been split yet
+	resplit := false
+	splitLast := func() {
+		if resplit {
+			e := &entries[len(entries)-1]
+			e.Args, e.Err = SplitWithOptions(e.Command, opts)
+			resplit = false
+		}
+	}
+	for i := 0; i < len(lines); i++ {
+		text := lines[i]
+		if t, ok := historyTimestamp(text); ok {
+			stamp, timestamps, pending = t, true, true
+			continue
+		}
+		if n := len(entries); n > 0 && !pending && timestamps {
+			entries[n-1].Command += "\n" + text
+			resplit = true
+			continue
+		}
+		splitLast()
+		entry := HistoryEntry{Command: text, Line: i + 1}
+		if pending {
+			entry.Time = stamp
+		}
+		entry.Args, entry.Err = SplitWithOptions(text, opts)
+		if !timestamps && incomplete(entry.Err) {
+			i += continueHistoryEntry(&entry, lines[i+1:], opts)
+		}
+		entries = append(entries, entry)
+		pending = false
+	}
+	splitLast()
+	return entries, readErr
+}
+
+// continueHistoryEntry joins the incomplete command of e with as many of
+// the following lines as it takes to complete it, looking ahead up to
+// historyContinuationLines lines, and returns the number of lines joined.
+// Nothing is joined if no line completes it.
+func continueHistoryEntry(e *HistoryEntry, next []string, opts *SplitOptions) int {
+	command := e.Command
+	for k := 0; k < len(next) && k < historyContinuationLines; k++ {
+		if _, ok := historyTimestamp(next[k]); ok {
+			break
+		}
+		command += "\n" + next[k]
+		args, err := SplitWithOptions(command, opts)
+		if !incomplete(err) {
+			e.Command, e.Args, e.Err = command, args, err
+			return k + 1
+		}
+	}
+	return 0
+}
+
+// historyTimestamp parses a timestamp line of a history file.
+func historyTimestamp(line string) (time.Time, bool) {
+	if len(line) < 2 || line[0] != '#' || strings.Trim(line[1:], "0123456789") != "" {
+		return time.Time{}, false
+	}
+	sec, err := strconv.ParseInt(line[1:], 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(sec, 0), true
+}
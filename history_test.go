@@ -0,0 +1,114 @@
+package shellquote
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHistoryExpansions(t *testing.T) {
+	for _, elem := range historyExpansionsTest {
+		offsets := HistoryExpansions(elem.input)
+		if !reflect.DeepEqual(offsets, elem.offsets) {
+			t.Errorf("Input %q, got %v, expected %v", elem.input, offsets, elem.offsets)
+		}
+	}
+}
+
+func TestEscapeHistory(t *testing.T) {
+	opts := &QuoteOptions{EscapeHistory: true}
+	for _, elem := range escapeHistoryTest {
+		output := JoinWithOptions(elem.input, opts)
+		if output != elem.output {
+			t.Errorf("Input %q, got %q, expected %q", elem.input, output, elem.output)
+		}
+		if offsets := HistoryExpansions(output); offsets != nil {
+			t.Errorf("Input %q, output %q still has history expansions at %v", elem.input, output, offsets)
+		}
+		if split, err := Split(output); err != nil || !reflect.DeepEqual(split, elem.input) {
+			t.Errorf("Input %q, output %q splits into %q (%v)", elem.input, output, split, err)
+		}
+	}
+}
+
+var historyExpansionsTest = []struct {
+	input   string
+	offsets []int
+}{
+	{"echo hello", nil},
+	{"echo !!", []int{5}},
+	{"echo \"wow!x\" 'wow!x' wow\\!x", []int{9}},
+	{"echo ! a!= b! \"c!\"", nil},
+	{"git commit -m \"fix!\" && echo p4ss!word", []int{33}},
+}
+
+var escapeHistoryTest = []struct {
+	input  []string
+	output string
+}{
+	{[]string{"hello!"}, "hello\\!"},
+	{[]string{"hello world!"}, "'hello world'\\!"},
+	{[]string{"it's done! really"}, "'it'\\''s done'\\!' really'"},
+	{[]string{"!! a"}, "\\!\\!' a'"},
+}
+
+func TestParseHistory(t *testing.T) {
+	for _, elem := range parseHistoryTest {
+		entries, err := ParseHistory(strings.NewReader(elem.input))
+		if err != nil {
+			t.Errorf("Input %q, got error %v", elem.input, err)
+			continue
+		}
+		if len(entries) != len(elem.entries) {
+			t.Errorf("Input %q, got %d entries, expected %d", elem.input, len(entries), len(elem.entries))
+			continue
+		}
+		for i, entry := range entries {
+			expected := elem.entries[i]
+			var unix int64
+			if !entry.Time.IsZero() {
+				unix = entry.Time.Unix()
+			}
+			if unix != expected.time || entry.Command != expected.command || entry.Line != expected.line ||
+				!reflect.DeepEqual(entry.Args, expected.args) || (entry.Err != nil) != expected.err {
+				t.Errorf("Input %q, entry %d is %+v, expected %+v", elem.input, i, entry, expected)
+			}
+		}
+	}
+}
+
+type historyEntryTest struct {
+	time    int64
+	command string
+	args    []string
+	err     bool
+	line    int
+}
+
+var parseHistoryTest = []struct {
+	input   string
+	entries []historyEntryTest
+}{
+	{"ls -l\necho 'a b' # note\n", []historyEntryTest{
+		{0, "ls -l", []string{"ls", "-l"}, false, 1},
+		{0, "echo 'a b' # note", []string{"echo", "a b"}, false, 2},
+	}},
+	{"echo 'multi\nline' $'x\\ty'\necho \"open", []historyEntryTest{
+		{0, "echo 'multi\nline' $'x\\ty'", []string{"echo", "multi\nline", "x\ty"}, false, 1},
+		{0, "echo \"open", []string{"echo", "\"open"}, true, 3},
+	}},
+	{"#1700000000\nfor f in *; do\n  echo \"$f\"\ndone\n#1700000060\ngit log | head\n#12ab\n", []historyEntryTest{
+		{1700000000, "for f in *; do\n  echo \"$f\"\ndone", []string{"for", "f", "in", "*;", "do", "echo", "$f", "done"}, false, 2},
+		{1700000060, "git log | head\n#12ab", []string{"git", "log", "|", "head"}, false, 6},
+	}},
+	{"x=$(date +%s)\n#1700000000\n", []historyEntryTest{
+		{0, "x=$(date +%s)", []string{"x=$(date +%s)"}, false, 1},
+	}},
+	{"ls\necho don't\ncd /tmp\necho \"a\n\nb\" \\\n  c\npwd\n", []historyEntryTest{
+		{0, "ls", []string{"ls"}, false, 1},
+		{0, "echo don't", []string{"echo", "don't"}, true, 2},
+		{0, "cd /tmp", []string{"cd", "/tmp"}, false, 3},
+		{0, "echo \"a\n\nb\" \\\n  c", []string{"echo", "a\n\nb", "c"}, false, 4},
+		{0, "pwd", []string{"pwd"}, false, 8},
+	}},
+}
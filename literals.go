@@ -0,0 +1,94 @@
+package shellquote
+
+import "strings"
+
+// Span is a quoted string literal found by Strings.
+type Span struct {
+	// Value is the content of the literal with quotes removed and escapes
+	// decoded.
+	Value string
+	// Quote is the opening quote: ', " or $'.
+	Quote string
+	// Start and End delimit the literal in the input, including its quotes.
+	Start, End int
+}
+
+// Strings returns the single-quoted, double-quoted and $'...' string
+// literals found in input, in the order they appear. The text around them
+// doesn't need to be valid shell syntax; only a backslash outside of quotes
+// is taken into account, since it escapes the character after it. A quote
+// that is never closed is skipped. Nothing is expanded, so the value of
+// "$HOME" is $HOME.
+func Strings(input string) []Span {
+	var spans []Span
+	for i := 0; i < len(input); {
+		c := input[i]
+		var span Span
+		var ok bool
+		switch {
+		case c == '\\':
+			i += 2
+			continue
+		case c == '\'':
+			span, ok = singleLiteral(input, i)
+		case c == '"':
+			span, ok = doubleLiteral(input, i)
+		case strings.HasPrefix(input[i:], "$'"):
+			span, ok = ansiLiteral(input, i)
+		}
+		if ok {
+			spans = append(spans, span)
+			i = span.End
+		} else {
+			i++
+		}
+	}
+	return spans
+}
+
+// singleLiteral returns the single-quoted string starting at input[i].
+func singleLiteral(input string, i int) (Span, bool) {
+	end := strings.IndexByte(input[i+1:], '\'')
+	if end < 0 {
+		return Span{}, false
+	}
+	return Span{Value: input[i+1 : i+1+end], Quote: "'", Start: i, End: i + end + 2}, true
+}
+
+// doubleLiteral returns the double-quoted string starting at input[i].
+func doubleLiteral(input string, i int) (Span, bool) {
+	var buf strings.Builder
+	for j := i + 1; j < len(input); j++ {
+		switch c := input[j]; {
+		case c == '"':
+			return Span{Value: buf.String(), Quote: `"`, Start: i, End: j + 1}, true
+		case c == '\\' && j+1 < len(input) && strings.IndexByte("$`\"\\\n", input[j+1]) >= 0:
+			j++
+			if input[j] != '\n' {
+				buf.WriteByte(input[j])
+			}
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return Span{}, false
+}
+
+// ansiLiteral returns the $'...' string starting at input[i].
+func ansiLiteral(input string, i int) (Span, bool) {
+	var buf strings.Builder
+	for j := i + 2; j < len(input); {
+		switch input[j] {
+		case '\'':
+			return Span{Value: buf.String(), Quote: "$'", Start: i, End: j + 1}, true
+		case '\\':
+			text, n, _, _ := decodeEscape(input[j:], ansiEscapes)
+			buf.WriteString(text)
+			j += n
+		default:
+			buf.WriteByte(input[j])
+			j++
+		}
+	}
+	return Span{}, false
+}
@@ -0,0 +1,26 @@
+package shellquote
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStrings(t *testing.T) {
+	for _, elem := range stringsTest {
+		output := Strings(elem.input)
+		if !reflect.DeepEqual(output, elem.output) {
+			t.Errorf("Input %q, got %+v, expected %+v", elem.input, output, elem.output)
+		}
+	}
+}
+
+var stringsTest = []struct {
+	input  string
+	output []Span
+}{
+	{"no literals here", nil},
+	{`cmd='a b' x="c \"d\" \q $e"`, []Span{{"a b", "'", 4, 9}, {`c "d" \q $e`, `"`, 12, 27}}},
+	{`<log> exec($'\x41\n') \'not' "multi` + "\nline\"", []Span{{"A\n", "$'", 11, 20}, {"multi\nline", `"`, 29, 41}}},
+	{`unclosed ' and "ok"`, []Span{{"ok", `"`, 15, 19}}},
+	{`"a'b" 'c"d'`, []Span{{"a'b", `"`, 0, 5}, {`c"d`, "'", 6, 11}}},
+}
@@ -0,0 +1,179 @@
+package shellquote
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// markdownLanguages maps the code block languages ParseMarkdownCommands
+// reads to whether the blocks hold terminal transcripts.
+var markdownLanguages = map[string]bool{
+	"sh":            false,
+	"bash":          false,
+	"shell":         false,
+	"console":       true,
+	"sh-session":    true,
+	"shell-session": true,
+}
+
+// MarkdownCommand is a command found in a Markdown document by
+// ParseMarkdownCommands.
+type MarkdownCommand struct {
+	// File is the name passed to ParseMarkdownCommands.
+	File string
+	// Language is the language of the code block, in lower case.
+	Language string
+	// Line is the 1-based number of the line in the document the command
+	// starts on.
+	Line int
+	// Command is the command's text, without prompts or the final newline.
+	Command string
+	// Args is Command split with bash's quoting rules.
+	Args []string
+	// Err is the error splitting Command, if any.
+	Err error
+	// Output is the output shown after the command in a transcript, as in
+	// TranscriptCommand.
+	Output string
+}
+
+// ParseMarkdownCommands reads a Markdown document from r and returns the
+// commands in its fenced code blocks marked as sh, bash or shell, and in
+// the terminal transcripts marked as console, sh-session or
+// shell-session. file is only used to fill in the File of each command.
+//
+// Script blocks are split into commands like SplitScript does, except that
+// a command that can't be split is returned with Err set instead of ending
+// the block. If the first non-blank line of a script block starts with a $
+// prompt, the block is read as a transcript anyway, as documentation often
+// does. Transcripts are read with ParseTranscript.
+//
+// Fences are recognized at any indentation, so that blocks inside list
+// items are found, and the content of a block loses as much indentation as
+// its opening fence has. A block without a closing fence extends to the
+// end of the document. The error returned by ParseMarkdownCommands is only
+// ever an error reading from r.
+func ParseMarkdownCommands(r io.Reader, file string) ([]MarkdownCommand, error) {
+	var commands []MarkdownCommand
+	var fence, language string
+	var block strings.Builder
+	inBlock, indent, start := false, 0, 0
+	br := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		text, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return commands, err
+		} else if err == io.EOF && len(text) == 0 {
+			break
+		}
+		text = strings.TrimSuffix(strings.TrimSuffix(text, "\n"), "\r")
+
+		switch {
+		case !inBlock:
+			var info string
+			if fence, indent, info, inBlock = markdownFence(text); inBlock {
+				language, _, _ = strings.Cut(strings.ToLower(info), " ")
+				start = line + 1
+				block.Reset()
+			}
+		case isClosingFence(text, fence):
+			commands = appendMarkdownCommands(commands, block.String(), file, language, start)
+			inBlock = false
+		default:
+			n := len(text) - len(strings.TrimLeft(text, " "))
+			block.WriteString(text[min(n, indent):])
+			block.WriteByte('\n')
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	if inBlock {
+		commands = appendMarkdownCommands(commands, block.String(), file, language, start)
+	}
+	return commands, nil
+}
+
+// markdownFence reports whether line opens a fenced code block, and
+// returns the fence, its indentation and the info string following it.
+func markdownFence(line string) (fence string, indent int, info string, ok bool) {
+	rest := strings.TrimLeft(line, " ")
+	indent = len(line) - len(rest)
+	if rest == "" || rest[0] != '`' && rest[0] != '~' {
+		return "", 0, "", false
+	}
+	n := len(rest) - len(strings.TrimLeft(rest, rest[:1]))
+	if n < 3 {
+		return "", 0, "", false
+	}
+	info = strings.TrimSpace(rest[n:])
+	if rest[0] == '`' && strings.IndexByte(info, '`') >= 0 {
+		return "", 0, "", false
+	}
+	return rest[:n], indent, info, true
+}
+
+// isClosingFence reports whether line closes the block opened by fence: it
+// holds at least as many of the same fence characters and nothing else.
+func isClosingFence(line, fence string) bool {
+	line = strings.TrimSpace(line)
+	return len(line) >= len(fence) && strings.Trim(line, fence[:1]) == ""
+}
+
+// appendMarkdownCommands appends the commands of a code block starting on
+// line start.
+func appendMarkdownCommands(commands []MarkdownCommand, block, file, language string, start int) []MarkdownCommand {
+	transcript, ok := markdownLanguages[language]
+	if !ok {
+		return commands
+	}
+	tOpts := DefaultTranscriptOptions()
+	if !transcript {
+		// # starts comments in scripts, so only $ prompts are accepted
+		tOpts.PromptChars = "$"
+		first, _, _ := strings.Cut(strings.TrimLeft(block, " \t\r\n"), "\n")
+		transcript = tOpts.promptLen(first) >= 0
+	}
+
+	opts := bashSplitOptions()
+	opts.CommentChar = '#'
+	opts.Substitutions = true
+	opts.CollectErrors = true
+	if transcript {
+		tOpts.Split = opts
+		found, _ := ParseTranscript(strings.NewReader(block), tOpts)
+		for _, c := range found {
+			commands = append(commands, MarkdownCommand{
+				File:     file,
+				Language: language,
+				Line:     start + c.Line - 1,
+				Command:  c.Command,
+				Args:     c.Args,
+				Err:      c.Err,
+				Output:   c.Output,
+			})
+		}
+		return commands
+	}
+
+	br := bufio.NewReader(strings.NewReader(block))
+	line := start
+	for {
+		args, raw, err := ReadCommand(br, opts)
+		if err == io.EOF {
+			return commands
+		}
+		if len(args) > 0 || err != nil {
+			commands = append(commands, MarkdownCommand{
+				File:     file,
+				Language: language,
+				Line:     line,
+				Command:  strings.TrimSuffix(raw, "\n"),
+				Args:     args,
+				Err:      err,
+			})
+		}
+		line += strings.Count(raw, "\n")
+	}
+}
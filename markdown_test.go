@@ -0,0 +1,58 @@
+package shellquote
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseMarkdownCommands(t *testing.T) {
+	input := "# Usage\n" +
+		"\n" +
+		"```bash\n" +
+		"# build it\n" +
+		"make \\\n" +
+		"  PREFIX=/usr\n" +
+		"echo 'done'\n" +
+		"```\n" +
+		"\n" +
+		"1. Then run:\n" +
+		"\n" +
+		"   ~~~~ Console\n" +
+		"   $ ./tool --help\n" +
+		"   usage: tool\n" +
+		"   ```\n" +
+		"   ~~~~~\n" +
+		"\n" +
+		"```python\n" +
+		"print('not shell')\n" +
+		"```\n" +
+		"```sh\n" +
+		"$ ls\n" +
+		"README.md\n" +
+		"```\n" +
+		"````sh\n" +
+		"echo 'open\n"
+	commands, err := ParseMarkdownCommands(strings.NewReader(input), "README.md")
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	var errs []bool
+	for i := range commands {
+		errs = append(errs, commands[i].Err != nil)
+		commands[i].Err = nil
+	}
+	expected := []MarkdownCommand{
+		{File: "README.md", Language: "bash", Line: 5, Command: "make \\\n  PREFIX=/usr", Args: []string{"make", "PREFIX=/usr"}},
+		{File: "README.md", Language: "bash", Line: 7, Command: "echo 'done'", Args: []string{"echo", "done"}},
+		{File: "README.md", Language: "console", Line: 13, Command: "./tool --help", Args: []string{"./tool", "--help"}, Output: "usage: tool\n```"},
+		{File: "README.md", Language: "sh", Line: 22, Command: "ls", Args: []string{"ls"}, Output: "README.md"},
+		{File: "README.md", Language: "sh", Line: 26, Command: "echo 'open", Args: []string{"echo", "'open"}},
+	}
+	if !reflect.DeepEqual(commands, expected) {
+		t.Errorf("got\n%+v\nexpected\n%+v", commands, expected)
+	}
+	if expected := []bool{false, false, false, false, true}; !reflect.DeepEqual(errs, expected) {
+		t.Errorf("got errors %v, expected %v", errs, expected)
+	}
+}
@@ -0,0 +1,234 @@
+package shellquote
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	UnsupportedTypeError = errors.New("Unsupported type")
+	InvalidTagError      = errors.New("Invalid cmd tag")
+)
+
+// Marshal returns the arguments described by the struct v, or a pointer to
+// one, without a program name. Use Join to turn them into a command line.
+//
+// Only fields with a cmd tag are used. The tag holds the option as it is
+// written, like "-o" or "--output", optionally followed by a comma and a
+// list of comma-separated flags:
+//
+//	omitempty   leave the option out if the field has its zero value
+//	eq          write --name=value, or -ovalue for a short option, instead
+//	            of the option and its value as two arguments
+//	positional  make the field an operand instead of an option, as in
+//	            cmd:",positional"
+//
+// A bool field adds its option if it is true and nothing otherwise. Fields
+// holding a string, an integer or a floating-point number, a type
+// implementing encoding.TextMarshaler, a pointer to one of these, or a
+// slice of them are converted to text; a nil pointer is left out and a
+// slice repeats the option for each element. Anonymous struct fields
+// without a tag are searched for further fields.
+//
+// Options are returned in field order, followed by the operands. If an
+// operand starts with -, a -- argument is put in front of them. An error
+// wrapping UnsupportedTypeError or InvalidTagError is returned if v or one
+// of its fields can't be used.
+func Marshal(v any) ([]string, error) {
+	rv, err := structValue(reflect.ValueOf(v))
+	if err != nil {
+		return nil, err
+	}
+	fields, err := cmdFields(rv.Type(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var args, operands []string
+	dashed := false
+	for _, f := range fields {
+		fv := rv.FieldByIndex(f.index)
+		if f.omitEmpty && fv.IsZero() {
+			continue
+		}
+		values, err := fieldTexts(fv, f)
+		if err != nil {
+			return nil, err
+		}
+		if f.positional {
+			for _, value := range values {
+				dashed = dashed || len(value) > 1 && value[0] == '-'
+			}
+			operands = append(operands, values...)
+			continue
+		}
+		for _, value := range values {
+			switch {
+			case isBoolField(fv):
+				args = append(args, f.option)
+			case f.joined && strings.HasPrefix(f.option, "--"):
+				args = append(args, f.option+"="+value)
+			case f.joined:
+				args = append(args, f.option+value)
+			default:
+				args = append(args, f.option, value)
+			}
+		}
+	}
+	if dashed {
+		args = append(args, "--")
+	}
+	return append(args, operands...), nil
+}
+
+// structValue returns the struct v holds or points to.
+func structValue(v reflect.Value) (reflect.Value, error) {
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return v, fmt.Errorf("%w: expected a struct, got nil", UnsupportedTypeError)
+	} else if v.Kind() != reflect.Struct {
+		return v, fmt.Errorf("%w: expected a struct, got %s", UnsupportedTypeError, v.Type())
+	}
+	return v, nil
+}
+
+// cmdField is a struct field with a cmd tag.
+type cmdField struct {
+	name  string
+	index []int
+	// option is the option as written, or "" for operands.
+	option     string
+	positional bool
+	omitEmpty  bool
+	joined     bool
+}
+
+// cmdFields returns the fields of t with a cmd tag, including those of
+// anonymous struct fields without one. index is the index of t within the
+// outermost struct.
+func cmdFields(t reflect.Type, index []int) ([]cmdField, error) {
+	var fields []cmdField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup("cmd")
+		fieldIndex := append(append([]int(nil), index...), i)
+		if !ok && sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			embedded, err := cmdFields(sf.Type, fieldIndex)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, embedded...)
+			continue
+		}
+		if !ok || tag == "-" || !sf.IsExported() {
+			continue
+		}
+
+		option, flags, _ := strings.Cut(tag, ",")
+		f := cmdField{name: sf.Name, index: fieldIndex, option: option}
+		for _, flag := range strings.Split(flags, ",") {
+			switch flag {
+			case "":
+			case "omitempty":
+				f.omitEmpty = true
+			case "eq":
+				f.joined = true
+			case "positional":
+				f.positional = true
+			default:
+				return nil, fmt.Errorf("%w: unknown flag %q on field %s", InvalidTagError, flag, sf.Name)
+			}
+		}
+		switch {
+		case f.positional && option != "":
+			return nil, fmt.Errorf("%w: positional field %s has option %q", InvalidTagError, sf.Name, option)
+		case !f.positional && !isOptionName(option):
+			return nil, fmt.Errorf("%w: field %s has option %q", InvalidTagError, sf.Name, option)
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// isOptionName reports whether option is a short option like -o or a long
+// one like --output.
+func isOptionName(option string) bool {
+	if strings.HasPrefix(option, "--") {
+		return len(option) > 2 && !strings.ContainsAny(option, "= \t\n")
+	}
+	return len(option) == 2 && option[0] == '-' && option[1] != '-' && option[1] != ':'
+}
+
+// isBoolField reports whether v, or the value it points to, is a bool.
+func isBoolField(v reflect.Value) bool {
+	t := v.Type()
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Bool
+}
+
+// fieldTexts returns the text of each value held by the field v. A bool
+// option gives a single empty text if it is set and none otherwise.
+func fieldTexts(v reflect.Value, f cmdField) ([]string, error) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Bool && !f.positional {
+		if v.Bool() {
+			return []string{""}, nil
+		}
+		return nil, nil
+	}
+	if v.Kind() == reflect.Slice && !v.Type().Implements(textMarshalerType) {
+		texts := make([]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			text, err := valueText(v.Index(i), f)
+			if err != nil {
+				return nil, err
+			}
+			texts = append(texts, text)
+		}
+		return texts, nil
+	}
+	text, err := valueText(v, f)
+	if err != nil {
+		return nil, err
+	}
+	return []string{text}, nil
+}
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// valueText converts the single value v of the field f to text.
+func valueText(v reflect.Value, f cmdField) (string, error) {
+	if v.Kind() != reflect.Pointer && v.CanAddr() && v.Addr().Type().Implements(textMarshalerType) {
+		v = v.Addr()
+	}
+	if v.Type().Implements(textMarshalerType) {
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(text), err
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), nil
+	}
+	return "", fmt.Errorf("%w: field %s has type %s", UnsupportedTypeError, f.name, v.Type())
+}
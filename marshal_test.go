@@ -0,0 +1,86 @@
+package shellquote
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+)
+
+type marshalBase struct {
+	Verbose bool `cmd:"-v"`
+}
+
+type marshalArgs struct {
+	marshalBase
+	Output   string   `cmd:"--output"`
+	Level    int      `cmd:"-l,eq"`
+	Ratio    float64  `cmd:"--ratio,eq,omitempty"`
+	Tags     []string `cmd:"--tag"`
+	Addr     net.IP   `cmd:"--addr,omitempty"`
+	Limit    *uint    `cmd:"--limit"`
+	Force    *bool    `cmd:"--force"`
+	Ignored  string
+	Skipped  string   `cmd:"-"`
+	Source   string   `cmd:",positional"`
+	Targets  []string `cmd:",positional"`
+	internal string
+}
+
+func TestMarshal(t *testing.T) {
+	limit, force := uint(3), false
+	for _, elem := range []struct {
+		input  any
+		output []string
+	}{
+		{marshalArgs{}, []string{"--output", "", "-l0", ""}},
+		{&marshalArgs{
+			marshalBase: marshalBase{Verbose: true},
+			Output:      "out file",
+			Level:       -2,
+			Ratio:       0.5,
+			Tags:        []string{"a", "b"},
+			Addr:        net.IPv4(127, 0, 0, 1),
+			Limit:       &limit,
+			Force:       &force,
+			Ignored:     "x",
+			Skipped:     "y",
+			Source:      "src",
+			Targets:     []string{"-dst", "x"},
+		}, []string{"-v", "--output", "out file", "-l-2", "--ratio=0.5", "--tag", "a", "--tag", "b", "--addr", "127.0.0.1", "--limit", "3", "--", "src", "-dst", "x"}},
+	} {
+		output, err := Marshal(elem.input)
+		if err != nil {
+			t.Errorf("Input %+v, got error %v", elem.input, err)
+		} else if !reflect.DeepEqual(output, elem.output) {
+			t.Errorf("Input %+v, got %q, expected %q", elem.input, output, elem.output)
+		}
+	}
+}
+
+func TestMarshalError(t *testing.T) {
+	for _, elem := range []struct {
+		input any
+		err   error
+	}{
+		{nil, UnsupportedTypeError},
+		{"string", UnsupportedTypeError},
+		{(*marshalArgs)(nil), UnsupportedTypeError},
+		{struct {
+			M map[string]string `cmd:"--m"`
+		}{}, UnsupportedTypeError},
+		{struct {
+			S string `cmd:"output"`
+		}{}, InvalidTagError},
+		{struct {
+			S string `cmd:"-o,positional"`
+		}{}, InvalidTagError},
+		{struct {
+			S string `cmd:"-o,required"`
+		}{}, InvalidTagError},
+	} {
+		if _, err := Marshal(elem.input); !errors.Is(err, elem.err) {
+			t.Errorf("Input %#v, got error %v, expected %v", elem.input, err, elem.err)
+		}
+	}
+}
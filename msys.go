@@ -0,0 +1,62 @@
+package shellquote
+
+import "strings"
+
+// msysExclude is the assignment that turns off MSYS2's argument conversion
+// for every argument of a command.
+const msysExclude = "MSYS2_ARG_CONV_EXCL='*'"
+
+// JoinMSYS quotes and joins args like JoinWithOptions for an MSYS2 or Git
+// Bash shell starting a native Windows program. Those shells convert
+// arguments that look like POSIX paths or path lists, so /tmp may arrive
+// as C:/msys64/tmp and --root=/ as --root=C:/msys64/. Quoting doesn't
+// prevent this, as the conversion happens after the shell has removed the
+// quotes. If any argument after the program name looks like it would be
+// converted, JoinMSYS therefore prefixes the command with
+// MSYS2_ARG_CONV_EXCL='*', which excludes all arguments from conversion.
+func JoinMSYS(args []string, opts *QuoteOptions) string {
+	joined := JoinWithOptions(args, opts)
+	for i := 1; i < len(args); i++ {
+		if msysConvertible(args[i]) {
+			return msysExclude + " " + joined
+		}
+	}
+	return joined
+}
+
+// msysConvertible approximates the MSYS2 runtime's check whether arg is
+// converted to a Windows path: it contains a / at its start, after an = as
+// in --opt=/path, or after a : separating the elements of a path list.
+// Drive letters and URLs are left alone.
+func msysConvertible(arg string) bool {
+	for i := strings.IndexByte(arg, '/'); i >= 0; i = nextIndexByte(arg, '/', i) {
+		switch {
+		case i == 0:
+			return true
+		case arg[i-1] == '=':
+			return true
+		case arg[i-1] == ':' && !strings.HasPrefix(arg[i:], "//") && !isDriveLetter(arg[:i-1]):
+			return true
+		}
+	}
+	return false
+}
+
+// nextIndexByte returns the index of the first c in s after i, or -1.
+func nextIndexByte(s string, c byte, i int) int {
+	if j := strings.IndexByte(s[i+1:], c); j >= 0 {
+		return i + 1 + j
+	}
+	return -1
+}
+
+// isDriveLetter reports whether the text before a colon makes it part of a
+// drive letter like C:.
+func isDriveLetter(before string) bool {
+	if len(before) == 0 {
+		return false
+	}
+	c := before[len(before)-1]
+	isLetter := 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+	return isLetter && (len(before) == 1 || strings.IndexByte("=;:", before[len(before)-2]) >= 0)
+}
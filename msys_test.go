@@ -0,0 +1,24 @@
+package shellquote
+
+import "testing"
+
+func TestJoinMSYS(t *testing.T) {
+	for _, elem := range joinMSYSTest {
+		output := JoinMSYS(elem.input, nil)
+		if output != elem.output {
+			t.Errorf("Input %q, got %q, expected %q", elem.input, output, elem.output)
+		}
+	}
+}
+
+var joinMSYSTest = []struct {
+	input  []string
+	output string
+}{
+	{[]string{"/usr/bin/git", "status", "-s"}, "/usr/bin/git status -s"},
+	{[]string{"cmd", "/c", "dir"}, "MSYS2_ARG_CONV_EXCL='*' cmd /c dir"},
+	{[]string{"tool", "--root=/srv", "a b"}, "MSYS2_ARG_CONV_EXCL='*' tool --root=/srv 'a b'"},
+	{[]string{"tool", "x:/a:/b"}, "MSYS2_ARG_CONV_EXCL='*' tool x:/a:/b"},
+	{[]string{"tool", "C:/Windows", "--dir=D:/x", "https://example.com/a", "a/b"}, "tool C:/Windows --dir=D:/x https://example.com/a a/b"},
+	{[]string{"tool"}, "tool"},
+}
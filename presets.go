@@ -0,0 +1,123 @@
+package shellquote
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var UnknownPresetError = errors.New("Unknown preset")
+
+var (
+	presetsMu sync.RWMutex
+	presets   = map[string]func() *SplitOptions{
+		"posix":        DefaultSplitOptions,
+		"bash":         bashSplitOptions,
+		"dash":         DefaultSplitOptions,
+		"no-escape":    NoEscapeSplitOptions,
+		"cmd":          cmdSplitOptions,
+		"batch":        batchSplitOptions,
+		"powershell":   powerShellSplitOptions,
+		"python-shlex": pythonShlexSplitOptions,
+	}
+)
+
+// Preset returns a new copy of the split options registered under name.
+// Names are matched case-insensitively. The built-in presets are "posix",
+// "bash", "dash", "no-escape", "cmd", "batch", "powershell" and
+// "python-shlex".
+//
+// If no preset with the given name exists, an error wrapping
+// UnknownPresetError is returned.
+func Preset(name string) (*SplitOptions, error) {
+	presetsMu.RLock()
+	fn, ok := presets[normalizePresetName(name)]
+	presetsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", UnknownPresetError, name)
+	}
+	return fn(), nil
+}
+
+// RegisterPreset makes the split options returned by fn available under the
+// given name. fn is called on every lookup and must return a new value each
+// time. RegisterPreset panics if fn is nil or if a preset with the same name
+// already exists.
+func RegisterPreset(name string, fn func() *SplitOptions) {
+	if fn == nil {
+		panic("shellquote: RegisterPreset called with nil function")
+	}
+	key := normalizePresetName(name)
+	presetsMu.Lock()
+	defer presetsMu.Unlock()
+	if _, dup := presets[key]; dup {
+		panic("shellquote: RegisterPreset called twice for preset " + key)
+	}
+	presets[key] = fn
+}
+
+// PresetNames returns the sorted names of all registered presets.
+func PresetNames() []string {
+	presetsMu.RLock()
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	presetsMu.RUnlock()
+	sort.Strings(names)
+	return names
+}
+
+func normalizePresetName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// bashSplitOptions enables the bash extensions to sh's quoting.
+func bashSplitOptions() *SplitOptions {
+	opts := DefaultSplitOptions()
+	opts.LocaleQuotes = true
+	opts.AnsiCQuotes = true
+	return opts
+}
+
+// cmdSplitOptions approximates cmd.exe: only double-quotes group words, and
+// the caret escapes the next character outside of them.
+func cmdSplitOptions() *SplitOptions {
+	opts := DefaultSplitOptions()
+	opts.SplitChars = " \t"
+	opts.SingleChar = 0
+	opts.EscapeChar = '^'
+	opts.DoubleEscapeChars = ""
+	return opts
+}
+
+// batchSplitOptions splits lines of batch files like cmdSplitOptions, and
+// additionally turns each %% into a single %.
+func batchSplitOptions() *SplitOptions {
+	opts := cmdSplitOptions()
+	opts.TransformWord = func(tok Token) (string, bool) {
+		return strings.Replace(tok.Value, "%%", "%", -1), true
+	}
+	return opts
+}
+
+// powerShellSplitOptions approximates PowerShell's quoting with the backtick
+// as escape character. Special sequences like `n and doubled single-quotes
+// inside single-quoted strings are not interpreted.
+func powerShellSplitOptions() *SplitOptions {
+	opts := DefaultSplitOptions()
+	opts.EscapeChar = '`'
+	opts.DoubleEscapeChars = "$`\""
+	return opts
+}
+
+// pythonShlexSplitOptions mirrors Python's shlex.split in POSIX mode, which
+// only allows escaping quotes and backslashes inside double-quotes.
+func pythonShlexSplitOptions() *SplitOptions {
+	opts := DefaultSplitOptions()
+	opts.SplitChars = " \t\r\n"
+	opts.DoubleEscapeChars = "\"\\"
+	return opts
+}
@@ -0,0 +1,80 @@
+package shellquote
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPresetSplit(t *testing.T) {
+	for _, elem := range presetSplitTest {
+		opts, err := Preset(elem.preset)
+		if err != nil {
+			t.Errorf("Preset %q, got error %v", elem.preset, err)
+			continue
+		}
+		output, err := SplitWithOptions(elem.input, opts)
+		if err != nil {
+			t.Errorf("Preset %q, input %q, got error %v", elem.preset, elem.input, err)
+		} else if !reflect.DeepEqual(output, elem.output) {
+			t.Errorf("Preset %q, input %q, got %q, expected %q", elem.preset, elem.input, output, elem.output)
+		}
+	}
+}
+
+func TestPresetUnknown(t *testing.T) {
+	if _, err := Preset("fish"); !errors.Is(err, UnknownPresetError) {
+		t.Errorf("got error %v, expected UnknownPresetError", err)
+	}
+}
+
+func TestPresetReturnsCopy(t *testing.T) {
+	a, _ := Preset("posix")
+	a.Limit = 3
+	b, _ := Preset("posix")
+	if b.Limit != -1 {
+		t.Errorf("modifying a preset result changed the preset")
+	}
+}
+
+func TestRegisterPreset(t *testing.T) {
+	RegisterPreset("Test-Comma", func() *SplitOptions {
+		opts := DefaultSplitOptions()
+		opts.SplitChars = ","
+		return opts
+	})
+	opts, err := Preset(" test-comma ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, _ := SplitWithOptions("a,'b,c'", opts)
+	if expected := []string{"a", "b,c"}; !reflect.DeepEqual(output, expected) {
+		t.Errorf("got %q, expected %q", output, expected)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("registering a duplicate preset did not panic")
+		}
+	}()
+	RegisterPreset("posix", DefaultSplitOptions)
+}
+
+var presetSplitTest = []struct {
+	preset string
+	input  string
+	output []string
+}{
+	{"posix", "a 'b c' \"d\\$\"", []string{"a", "b c", "d$"}},
+	{"BASH", "a\\ b", []string{"a b"}},
+	{"bash", "echo $\"hello world\" $x", []string{"echo", "hello world", "$x"}},
+	{"posix", "echo $\"hello world\"", []string{"echo", "$hello world"}},
+	{"bash", "echo $'a\\tb'", []string{"echo", "a\tb"}},
+	{"dash", "echo $'a\\tb'", []string{"echo", "$a\\tb"}},
+	{"no-escape", "C:\\dir\\file x", []string{"C:\\dir\\file", "x"}},
+	{"cmd", "copy \"C:\\My Files\\a.txt\" it's^ here", []string{"copy", "C:\\My Files\\a.txt", "it's here"}},
+	{"cmd", "echo \"a^b\"", []string{"echo", "a^b"}},
+	{"batch", "echo 100%% \"50%%\" a^&b", []string{"echo", "100%", "50%", "a&b"}},
+	{"powershell", "Write-Host 'a b' \"c`\"d\" e` f", []string{"Write-Host", "a b", "c\"d", "e f"}},
+	{"python-shlex", "a \"b\\$c\" d\re", []string{"a", "b\\$c", "d", "e"}},
+}
@@ -0,0 +1,198 @@
+package shellquote
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+)
+
+// Assignment is a variable assignment found by ParseProfile.
+type Assignment struct {
+	Name  string
+	Value string
+	// Export reports whether the variable was exported, either by the
+	// assignment itself or by a later export of the name, and not
+	// unexported again by a later export -n.
+	Export bool
+	// Literal reports whether the value is free of expansions, so that
+	// Value is exactly what the shell would assign. Otherwise Value holds
+	// the unquoted but unexpanded text, like $HOME/bin.
+	Literal bool
+	// Line is the 1-based number of the line the assignment is on.
+	Line int
+}
+
+// ParseProfile makes a best-effort attempt at reading the variables set by
+// a shell startup file like .profile, without running any of it. It
+// returns plain assignments like NAME=value standing alone, as well as
+// those made by export, in the order they appear, with their values
+// unquoted as bash would. export -n removes the export attribute, and
+// export -f, which exports functions, is ignored. Assignments are found
+// wherever a simple command may start, including inside if statements and
+// loops, since conditions are not evaluated. Other commands, and
+// assignments that only apply to a command they precede, are ignored, as
+// are lines that cannot be split.
+//
+// The only errors returned are those from reading r.
+func ParseProfile(r io.Reader) ([]Assignment, error) {
+	opts, _ := Preset("bash")
+	opts.CommentChar = '#'
+	opts.DetailedErrors = true
+	literal := opts.Clone()
+	literal.RejectExpansions = true
+
+	var assignments []Assignment
+	br := bufio.NewReader(r)
+	line := 1
+	for {
+		_, raw, err := ReadCommand(br, opts)
+		if err == io.EOF {
+			return assignments, nil
+		}
+		var splitErr *SplitError
+		if err != nil && !errors.As(err, &splitErr) {
+			return assignments, err
+		}
+		if err == nil {
+			cmds, offsets := simpleCommands(raw)
+			for i, cmd := range cmds {
+				cmdLine := line + strings.Count(raw[:offsets[i]], "\n")
+				assignments = parseAssignments(assignments, cmd, cmdLine, opts, literal)
+			}
+		}
+		line += strings.Count(raw, "\n")
+	}
+}
+
+// profileKeywords are the reserved words that may precede a simple command.
+var profileKeywords = map[string]bool{
+	"if": true, "then": true, "elif": true, "else": true,
+	"while": true, "until": true, "do": true, "{": true, "!": true,
+}
+
+// parseAssignments appends the assignments made by the simple command cmd,
+// which starts on the given line, to assignments.
+func parseAssignments(assignments []Assignment, cmd string, line int, opts, literal *SplitOptions) []Assignment {
+	tokens, err := SplitTokens(cmd, opts)
+	if err != nil {
+		return assignments
+	}
+	for len(tokens) > 0 && profileKeywords[tokens[0].Value] {
+		tokens = tokens[1:]
+	}
+	export, exported := len(tokens) > 0 && tokens[0].Value == "export", true
+	if export {
+		tokens = tokens[1:]
+		// -n removes the export attribute, -f exports functions instead of
+		// variables and -p only lists exported variables
+		for len(tokens) > 0 && len(tokens[0].Value) > 1 && tokens[0].Value[0] == '-' {
+			opt := tokens[0].Value
+			tokens = tokens[1:]
+			if opt == "--" {
+				break
+			}
+			if strings.Contains(opt, "f") {
+				return assignments
+			}
+			if strings.Contains(opt, "n") {
+				exported = false
+			}
+		}
+	}
+
+	var found []Assignment
+	for _, tok := range tokens {
+		// an assignment word must not have quotes or escapes in its name
+		n := assignmentNameLen(tok.Value)
+		if n == 0 || tok.InputOffset(n) != tok.Start+n {
+			if !export {
+				// assignments preceding a command only apply to it
+				return assignments
+			}
+			if nameLen(tok.Value) == len(tok.Value) {
+				markExported(assignments, tok.Value, exported)
+			}
+			continue
+		}
+		_, err := SplitWithOptions(cmd[tok.Start:tok.End], literal)
+		value := cmd[tok.Start+n+1 : tok.End]
+		found = append(found, Assignment{
+			Name:    tok.Value[:n],
+			Value:   tok.Value[n+1:],
+			Export:  export && exported,
+			Literal: err == nil && !strings.HasPrefix(value, "~") && !strings.Contains(value, ":~"),
+			Line:    line + strings.Count(cmd[:tok.Start], "\n"),
+		})
+	}
+	return append(assignments, found...)
+}
+
+// markExported sets whether the last assignment to name is exported.
+func markExported(assignments []Assignment, name string, exported bool) {
+	for i := len(assignments) - 1; i >= 0; i-- {
+		if assignments[i].Name == name {
+			assignments[i].Export = exported
+			return
+		}
+	}
+}
+
+// simpleCommands splits text at the unquoted control operators ;, &, | and
+// newlines, which separate simple commands, and drops comments. Operators
+// inside command substitutions and parameter expansions are kept. The
+// offset of each command within text is returned along with it.
+func simpleCommands(text string) (cmds []string, offsets []int) {
+	start, depth := 0, 0
+	wordStart := true
+	for i := 0; i < len(text); {
+		c := text[i]
+		rest := text[i:]
+		atWordStart := wordStart
+		wordStart = strings.IndexByte(" \t\n;&|(", c) >= 0
+
+		switch {
+		case c == '\\':
+			i += 2
+		case c == '\'':
+			i = skipSingleQuoted(text, i)
+		case c == '"':
+			i = skipDoubleQuoted(text, i)
+		case strings.HasPrefix(rest, "$'"):
+			i = skipANSIQuoted(text, i+1)
+		case c == '`':
+			if end := strings.IndexByte(text[i+1:], '`'); end >= 0 {
+				i += end + 2
+			} else {
+				i = len(text)
+			}
+		case strings.HasPrefix(rest, "$(") || strings.HasPrefix(rest, "${"):
+			depth++
+			i += 2
+		case depth > 0 && c == '(':
+			depth++
+			i++
+		case depth > 0 && (c == ')' || c == '}'):
+			depth--
+			i++
+		case depth == 0 && c == '#' && atWordStart:
+			cmds, offsets = append(cmds, text[start:i]), append(offsets, start)
+			if end := strings.IndexByte(rest, '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(text)
+			}
+			start = i
+		case depth == 0 && strings.IndexByte(";&|\n", c) >= 0:
+			cmds, offsets = append(cmds, text[start:i]), append(offsets, start)
+			i++
+			start = i
+		default:
+			i++
+		}
+	}
+	if start < len(text) {
+		cmds, offsets = append(cmds, text[start:]), append(offsets, start)
+	}
+	return cmds, offsets
+}
@@ -0,0 +1,66 @@
+package shellquote
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseProfile(t *testing.T) {
+	profile := `# ~/.profile
+export EDITOR=vim
+PATH="$HOME/bin:$PATH"; export PATH
+if [ -d /opt/go ]; then
+    GOROOT=/opt/go # comment; NOT=this
+fi
+LANG=C make # prefix assignment
+A=1 B='two words' C=$'tab\there'
+export -n D=4 E="multi
+line"
+MSG="it's; \"fine\"" && echo done
+TILDE=~/x
+export -p GOROOT; export -n EDITOR; export -f A
+if IN_IF=1; then :; elif IN_ELIF=2; then :; fi; while ! LOOP=3; do :; done
+'broken=1' echo "unterminated
+`
+	assignments, err := ParseProfile(strings.NewReader(profile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Assignment{
+		{Name: "EDITOR", Value: "vim", Literal: true, Line: 2},
+		{Name: "PATH", Value: "$HOME/bin:$PATH", Export: true, Line: 3},
+		{Name: "GOROOT", Value: "/opt/go", Export: true, Literal: true, Line: 5},
+		{Name: "A", Value: "1", Literal: true, Line: 8},
+		{Name: "B", Value: "two words", Literal: true, Line: 8},
+		{Name: "C", Value: "tab\there", Literal: true, Line: 8},
+		{Name: "D", Value: "4", Literal: true, Line: 9},
+		{Name: "E", Value: "multi\nline", Literal: true, Line: 9},
+		{Name: "MSG", Value: "it's; \"fine\"", Literal: true, Line: 11},
+		{Name: "TILDE", Value: "~/x", Line: 12},
+		{Name: "IN_IF", Value: "1", Literal: true, Line: 14},
+		{Name: "IN_ELIF", Value: "2", Literal: true, Line: 14},
+		{Name: "LOOP", Value: "3", Literal: true, Line: 14},
+	}
+	if !reflect.DeepEqual(assignments, expected) {
+		t.Errorf("got %+v, expected %+v", assignments, expected)
+	}
+}
+
+func TestSimpleCommands(t *testing.T) {
+	for _, elem := range simpleCommandsTest {
+		output, _ := simpleCommands(elem.input)
+		if !reflect.DeepEqual(output, elem.output) {
+			t.Errorf("Input %q, got %q, expected %q", elem.input, output, elem.output)
+		}
+	}
+}
+
+var simpleCommandsTest = []struct {
+	input  string
+	output []string
+}{
+	{"a; b && c | d &", []string{"a", " b ", "", " c ", " d "}},
+	{`a=$(x; y) b=${c:-;} 'd;' "e;" \; f#; g`, []string{`a=$(x; y) b=${c:-;} 'd;' "e;" \; f#`, " g"}},
+	{"a # b; c\nd `e;f`", []string{"a ", "", "d `e;f`"}},
+}
@@ -2,7 +2,10 @@ package shellquote
 
 import (
 	"bytes"
+	"fmt"
+	"reflect"
 	"strings"
+	"unicode"
 	"unicode/utf8"
 )
 
@@ -10,23 +13,192 @@ import (
 // If passed to /bin/sh, the resulting string will be split back into the
 // original arguments.
 func Join(args ...string) string {
+	return JoinWithOptions(args, nil)
+}
+
+// QuoteStyle selects how QuoteWithOptions and JoinWithOptions quote words.
+type QuoteStyle int
+
+const (
+	// DefaultStyle backslash-escapes special characters and switches to
+	// single-quotes for words containing whitespace.
+	DefaultStyle QuoteStyle = iota
+	// PrintfStyle produces the same output as bash's printf %q in a UTF-8
+	// locale: special characters are backslash-escaped, and words with
+	// control characters, invalid UTF-8 or other non-printable characters
+	// are written as $'...' strings, which need AnsiCQuotes to be split.
+	PrintfStyle
+	// ShortestStyle tries backslash-escaping, single-quoting and
+	// double-quoting each word, and uses whichever gives the shortest
+	// output. Ties are resolved in that order.
+	ShortestStyle
+)
+
+// AssignmentMode selects which words of the form NAME=value are quoted as
+// variable assignments.
+type AssignmentMode int
+
+const (
+	// NoAssignments quotes NAME=value words like any other.
+	NoAssignments AssignmentMode = iota
+	// LeadingAssignments quotes only the value of NAME=value words that
+	// come before any other word, where the shell treats them as variable
+	// assignments.
+	LeadingAssignments
+	// AllAssignments quotes only the value of every NAME=value word.
+	AllAssignments
+)
+
+// QuoteOptions configures JoinWithOptions and QuoteWithOptions. The zero
+// value quotes like Join.
+type QuoteOptions struct {
+	Style QuoteStyle
+
+	// EscapeHistory keeps every ! outside of quotes and escapes it with a
+	// backslash, so the output can be pasted into interactive bash or csh
+	// sessions without triggering history expansion.
+	// Inside $'...' strings, a ! is written as \041.
+	EscapeHistory bool
+
+	// ASCIIOnly guarantees that the output consists of printable ASCII
+	// characters only. Words containing anything else are written as $'...'
+	// strings, with \uXXXX or \UXXXXXXXX escapes for non-ASCII characters
+	// and \xHH escapes for bytes that aren't valid UTF-8. Such strings need
+	// AnsiCQuotes to be split.
+	ASCIIOnly bool
+
+	// EscapeNonPrintable writes words containing control characters,
+	// invalid UTF-8 or other non-printable characters as $'...' strings in
+	// every style, not just PrintfStyle, so that the output can be shown on
+	// a terminal safely. Printable non-ASCII characters are kept unless
+	// ASCIIOnly is set.
+	EscapeNonPrintable bool
+
+	// HexEscapes writes non-printable characters inside $'...' strings as
+	// \uXXXX or \UXXXXXXXX, and control characters without a short escape
+	// and invalid bytes as \xHH, instead of an octal escape for each byte.
+	HexEscapes bool
+
+	// Assignments makes NAME=value words keep NAME= unquoted and quote only
+	// the value, as in FOO='a b', so that the shell still sees an
+	// assignment. Quoting the whole word would turn it into a command name.
+	Assignments AssignmentMode
+
+	// EscapeGlobs applies GlobQuote to each word before quoting it, so that
+	// glob metacharacters are still backslash-escaped after the shell has
+	// removed the quoting. Use it for commands whose arguments go through
+	// another round of expansion, like the remote command run by ssh.
+	EscapeGlobs bool
+
+	// SSHTokens quotes words for OpenSSH ProxyCommand and Match exec
+	// values, in which ssh expands % tokens before running the command.
+	// Tokens like %h, and %% standing for a single %, are kept as they are,
+	// while any other % is doubled.
+	SSHTokens bool
+}
+
+func DefaultQuoteOptions() *QuoteOptions {
+	return &QuoteOptions{}
+}
+
+// JoinWithOptions quotes each argument according to opts and joins them
+// with a space.
+func JoinWithOptions(args []string, opts *QuoteOptions) string {
+	if opts == nil {
+		opts = DefaultQuoteOptions()
+	}
 	var buf bytes.Buffer
+	leading := true
 	for i, arg := range args {
 		if i != 0 {
 			buf.WriteByte(' ')
 		}
-		quote(arg, &buf)
+		if opts.EscapeGlobs {
+			arg = GlobQuote(arg)
+		}
+		if opts.SSHTokens {
+			arg = escapeSSHPercents(arg)
+		}
+		n := assignmentNameLen(arg)
+		if n == 0 {
+			leading = false
+		}
+		if n > 0 && (opts.Assignments == AllAssignments || opts.Assignments == LeadingAssignments && leading) {
+			quoteAssignment(arg[:n], arg[n+1:], &buf, opts)
+		} else {
+			quote(arg, &buf, opts)
+		}
 	}
 	return buf.String()
 }
 
+// Quote quotes a single argument like Join.
+func Quote(word string) string {
+	return QuoteWithOptions(word, nil)
+}
+
+// QuoteWithOptions quotes a single argument according to opts. With
+// LeadingAssignments or AllAssignments, a NAME=value word is quoted as an
+// assignment.
+func QuoteWithOptions(word string, opts *QuoteOptions) string {
+	return JoinWithOptions([]string{word}, opts)
+}
+
+// JoinAny converts each argument to a string like QuoteValue and joins
+// them like Join.
+func JoinAny(args ...any) string {
+	words := make([]string, len(args))
+	for i, arg := range args {
+		words[i] = valueString(arg)
+	}
+	return Join(words...)
+}
+
+// QuoteValue converts v to a string and quotes it like Quote. Strings are
+// used as they are and byte slices as the string they hold. nil, including
+// a nil pointer, gives an empty word. All other values are formatted with
+// fmt's %v verb, which uses the String or Error method of values having
+// one, so that a time.Duration becomes 1m30s.
+func QuoteValue(v any) string {
+	return Quote(valueString(v))
+}
+
+// valueString converts v to a string for QuoteValue.
+func valueString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer && rv.IsNil() {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
 const (
 	specialChars      = "\\'\"`${[|&;<>()*?!"
 	extraSpecialChars = " \t\n"
 	prefixChars       = "~"
 )
 
-func quote(word string, buf *bytes.Buffer) {
+func quote(word string, buf *bytes.Buffer, opts *QuoteOptions) {
+	if needsANSIC(word, opts) {
+		quoteANSIC(word, buf, opts)
+		return
+	}
+	switch opts.Style {
+	case PrintfStyle:
+		quotePrintf(word, buf, opts)
+		return
+	case ShortestStyle:
+		quoteShortest(word, buf, opts)
+		return
+	}
+
 	// We want to try to produce a "nice" output. As such, we will
 	// backslash-escape most characters, but if we encounter a space, or if we
 	// encounter an extra-special char (which doesn't work with
@@ -58,23 +230,59 @@ func quote(word string, buf *bytes.Buffer) {
 		} else if strings.ContainsRune(extraSpecialChars, c) {
 			// start over in quote mode
 			buf.Truncate(origLen)
-			goto quote
+			quoteSingle(word, buf, opts)
+			return
 		}
 		atStart = false
 	}
 	if len(prev) > 0 {
 		buf.WriteString(prev)
 	}
-	return
+}
+
+// needsANSIC reports whether opts require word to be written as a $'...'
+// string.
+func needsANSIC(word string, opts *QuoteOptions) bool {
+	return opts.ASCIIOnly && !isPrintableASCII(word) || opts.EscapeNonPrintable && !isPrintableString(word)
+}
+
+// assignmentNameLen returns the length of NAME if word is of the form
+// NAME=value, and 0 otherwise.
+func assignmentNameLen(word string) int {
+	n := nameLen(word)
+	if n == 0 || n == len(word) || word[n] != '=' {
+		return 0
+	}
+	return n
+}
+
+// quoteAssignment quotes the assignment of value to the variable name.
+func quoteAssignment(name, value string, buf *bytes.Buffer, opts *QuoteOptions) {
+	buf.WriteString(name)
+	buf.WriteByte('=')
+	if len(value) == 0 {
+		return
+	}
+	if strings.Contains(value, ":~") && opts.Style != PrintfStyle && !needsANSIC(value, opts) {
+		// the shell expands a tilde following a colon in assignments
+		quoteSingle(value, buf, opts)
+		return
+	}
+	quote(value, buf, opts)
+}
 
-quote:
-	// quote mode
+// quoteSingle quotes word with single-quotes.
+func quoteSingle(word string, buf *bytes.Buffer, opts *QuoteOptions) {
 	// Use single-quotes, but if we find a single-quote in the word, we need
 	// to terminate the string, emit an escaped quote, and start the string up
-	// again
+	// again. The same goes for ! if it has to stay out of quotes.
+	breakChars := "'"
+	if opts.EscapeHistory {
+		breakChars = "'!"
+	}
 	inQuote := false
 	for len(word) > 0 {
-		i := strings.IndexRune(word, '\'')
+		i := strings.IndexAny(word, breakChars)
 		if i == -1 {
 			break
 		}
@@ -85,12 +293,14 @@ quote:
 			}
 			buf.WriteString(word[0:i])
 		}
+		c := word[i]
 		word = word[i+1:]
 		if inQuote {
 			buf.WriteByte('\'')
 			inQuote = false
 		}
-		buf.WriteString("\\'")
+		buf.WriteByte('\\')
+		buf.WriteByte(c)
 	}
 	if len(word) > 0 {
 		if !inQuote {
@@ -100,3 +310,187 @@ quote:
 		buf.WriteByte('\'')
 	}
 }
+
+// doubleSpecialChars are the characters that keep their special meaning
+// inside double-quotes.
+const doubleSpecialChars = "\\\"`$"
+
+// quoteShortest quotes word in whichever style is shortest.
+func quoteShortest(word string, buf *bytes.Buffer, opts *QuoteOptions) {
+	if len(word) == 0 {
+		buf.WriteString("''")
+		return
+	}
+	var backslash, single, double bytes.Buffer
+	best := &single
+	quoteSingle(word, &single, opts)
+	if quoteBackslash(word, &backslash) && backslash.Len() <= best.Len() {
+		best = &backslash
+	}
+	if quoteDouble(word, &double, opts) && double.Len() < best.Len() {
+		best = &double
+	}
+	buf.Write(best.Bytes())
+}
+
+// quoteBackslash quotes word by backslash-escaping every special character.
+// It fails if word contains a newline, which can't be escaped that way.
+func quoteBackslash(word string, buf *bytes.Buffer) bool {
+	for i := 0; i < len(word); {
+		c, l := utf8.DecodeRuneInString(word[i:])
+		if c == '\n' {
+			return false
+		}
+		if strings.ContainsRune(specialChars, c) || strings.ContainsRune(extraSpecialChars, c) ||
+			i == 0 && strings.ContainsRune(prefixChars, c) {
+			buf.WriteByte('\\')
+		}
+		buf.WriteString(word[i : i+l])
+		i += l
+	}
+	return true
+}
+
+// quoteDouble quotes word with double-quotes. It fails if EscapeHistory is
+// set and word contains a !, which can't be escaped inside double-quotes.
+func quoteDouble(word string, buf *bytes.Buffer, opts *QuoteOptions) bool {
+	if opts.EscapeHistory && strings.ContainsRune(word, '!') {
+		return false
+	}
+	buf.WriteByte('"')
+	for i := 0; i < len(word); i++ {
+		c := word[i]
+		// a backslash is only special in front of another special character,
+		// including the closing quote
+		if strings.IndexByte(doubleSpecialChars, c) >= 0 &&
+			(c != '\\' || i+1 == len(word) || strings.IndexByte(doubleSpecialChars+"\n", word[i+1]) >= 0) {
+			buf.WriteByte('\\')
+		}
+		buf.WriteByte(c)
+	}
+	buf.WriteByte('"')
+	return true
+}
+
+// printfSpecialChars are the characters printf %q backslash-escapes
+// anywhere in a word.
+const printfSpecialChars = " !\"$&'()*,;<>?[\\]^`{|}"
+
+// quotePrintf quotes word like bash's printf %q.
+func quotePrintf(word string, buf *bytes.Buffer, opts *QuoteOptions) {
+	if len(word) == 0 {
+		buf.WriteString("''")
+		return
+	}
+	if !isPrintableString(word) {
+		quoteANSIC(word, buf, opts)
+		return
+	}
+
+	var prev rune
+	for i, c := range word {
+		if strings.ContainsRune(printfSpecialChars, c) ||
+			c == '#' && i == 0 ||
+			c == '~' && (i == 0 || prev == ':' || prev == '=') {
+			buf.WriteByte('\\')
+		}
+		buf.WriteRune(c)
+		prev = c
+	}
+}
+
+// quoteANSIC writes word as a $'...' string, escaping what isn't printable.
+func quoteANSIC(word string, buf *bytes.Buffer, opts *QuoteOptions) {
+	buf.WriteString("$'")
+	for len(word) > 0 {
+		c, l := utf8.DecodeRuneInString(word)
+		switch {
+		case c == utf8.RuneError && l == 1 && (opts.ASCIIOnly || opts.HexEscapes):
+			writeHex(buf, 'x', rune(word[0]), 2)
+		case c == utf8.RuneError && l == 1:
+			writeOctal(buf, word[:1])
+		case c == '\a':
+			buf.WriteString(`\a`)
+		case c == '\b':
+			buf.WriteString(`\b`)
+		case c == '\f':
+			buf.WriteString(`\f`)
+		case c == '\n':
+			buf.WriteString(`\n`)
+		case c == '\r':
+			buf.WriteString(`\r`)
+		case c == '\t':
+			buf.WriteString(`\t`)
+		case c == '\v':
+			buf.WriteString(`\v`)
+		case c == 0x1b:
+			buf.WriteString(`\E`)
+		case c == '\\' || c == '\'':
+			buf.WriteByte('\\')
+			buf.WriteRune(c)
+		case c == '!' && opts.EscapeHistory:
+			writeOctal(buf, "!")
+		case c >= utf8.RuneSelf && (opts.ASCIIOnly || opts.HexEscapes && !isPrintable(c)):
+			if c > 0xffff {
+				writeHex(buf, 'U', c, 8)
+			} else {
+				writeHex(buf, 'u', c, 4)
+			}
+		case isPrintable(c):
+			buf.WriteString(word[:l])
+		case opts.HexEscapes:
+			writeHex(buf, 'x', c, 2)
+		default:
+			writeOctal(buf, word[:l])
+		}
+		word = word[l:]
+	}
+	buf.WriteByte('\'')
+}
+
+// writeOctal writes each byte of s as a three digit octal escape.
+func writeOctal(buf *bytes.Buffer, s string) {
+	for i := 0; i < len(s); i++ {
+		buf.WriteByte('\\')
+		buf.WriteByte('0' + s[i]>>6)
+		buf.WriteByte('0' + s[i]>>3&7)
+		buf.WriteByte('0' + s[i]&7)
+	}
+}
+
+// writeHex writes an escape for v made up of a backslash, the given letter
+// and exactly digits hex digits.
+func writeHex(buf *bytes.Buffer, letter byte, v rune, digits int) {
+	buf.WriteByte('\\')
+	buf.WriteByte(letter)
+	for shift := 4 * (digits - 1); shift >= 0; shift -= 4 {
+		buf.WriteByte("0123456789abcdef"[v>>shift&0xf])
+	}
+}
+
+// isPrintableString reports whether s is valid UTF-8 consisting of
+// printable characters only.
+func isPrintableString(s string) bool {
+	return utf8.ValidString(s) && strings.IndexFunc(s, func(r rune) bool { return !isPrintable(r) }) < 0
+}
+
+// isPrintableASCII reports whether s consists of printable ASCII characters
+// only.
+func isPrintableASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < ' ' || s[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// isPrintable approximates the C library's iswprint in a UTF-8 locale,
+// which unlike unicode.IsPrint accepts all spaces other than the line and
+// paragraph separators as well as format and private use characters.
+func isPrintable(r rune) bool {
+	if r < utf8.RuneSelf {
+		return r >= ' ' && r != 0x7f
+	}
+	return unicode.IsGraphic(r) || unicode.In(r, unicode.Cf, unicode.Co)
+}
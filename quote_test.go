@@ -1,7 +1,14 @@
 package shellquote
 
 import (
+	"errors"
+	"math/rand"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestSimpleJoin(t *testing.T) {
@@ -13,6 +20,126 @@ func TestSimpleJoin(t *testing.T) {
 	}
 }
 
+func TestPrintfStyle(t *testing.T) {
+	for _, elem := range printfStyleTest {
+		opts := &QuoteOptions{Style: PrintfStyle, EscapeHistory: elem.history}
+		output := QuoteWithOptions(elem.input, opts)
+		if output != elem.output {
+			t.Errorf("Input %q, got %q, expected %q", elem.input, output, elem.output)
+		}
+	}
+}
+
+func TestPrintfStyleAgainstBash(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not found")
+	}
+	var inputs []string
+	for _, elem := range printfStyleTest {
+		if !elem.history {
+			inputs = append(inputs, elem.input)
+		}
+	}
+	alphabet := []string{"é", "\u200b", "\u00a0", "\u0085", "\u2028", "\ufffd", "\xff", "\xc3"}
+	for c := 1; c < 0x80; c++ {
+		alphabet = append(alphabet, string(rune(c)))
+	}
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		var word strings.Builder
+		for n := rnd.Intn(6); n > 0; n-- {
+			word.WriteString(alphabet[rnd.Intn(len(alphabet))])
+		}
+		inputs = append(inputs, word.String())
+	}
+
+	cmd := exec.Command(bash, append([]string{"-c", `printf '%q\n' "$@"`, "bash"}, inputs...)...)
+	cmd.Env = append(os.Environ(), "LC_ALL=C.UTF-8")
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+	if len(want) != len(inputs) {
+		t.Fatalf("got %d lines from bash, expected %d", len(want), len(inputs))
+	}
+	opts := &QuoteOptions{Style: PrintfStyle}
+	for i, input := range inputs {
+		if got := QuoteWithOptions(input, opts); got != want[i] {
+			t.Errorf("Input %q, bash gives %q, got %q", input, want[i], got)
+		}
+	}
+}
+
+func TestShortestStyle(t *testing.T) {
+	for _, elem := range shortestStyleTest {
+		opts := &QuoteOptions{Style: ShortestStyle, EscapeHistory: elem.history}
+		output := QuoteWithOptions(elem.input, opts)
+		if output != elem.output {
+			t.Errorf("Input %q, got %q, expected %q", elem.input, output, elem.output)
+		}
+	}
+}
+
+func TestASCIIOnly(t *testing.T) {
+	for _, elem := range asciiOnlyTest {
+		output := JoinWithOptions(elem.input, &QuoteOptions{Style: elem.style, ASCIIOnly: true})
+		if output != elem.output {
+			t.Errorf("Input %q, got %q, expected %q", elem.input, output, elem.output)
+		}
+	}
+}
+
+func TestRuneEscapes(t *testing.T) {
+	for _, elem := range runeEscapesTest {
+		output := QuoteWithOptions(elem.input, &elem.opts)
+		if output != elem.output {
+			t.Errorf("Input %q, options %+v, got %q, expected %q", elem.input, elem.opts, output, elem.output)
+		}
+	}
+}
+
+func TestAssignments(t *testing.T) {
+	for _, elem := range assignmentsTest {
+		output := JoinWithOptions(elem.input, &QuoteOptions{Style: elem.style, Assignments: elem.mode})
+		if output != elem.output {
+			t.Errorf("Input %q, got %q, expected %q", elem.input, output, elem.output)
+		}
+	}
+}
+
+func TestEscapeGlobs(t *testing.T) {
+	for _, elem := range escapeGlobsTest {
+		output := JoinWithOptions(elem.input, &QuoteOptions{Style: elem.style, EscapeGlobs: true, Assignments: LeadingAssignments})
+		if output != elem.output {
+			t.Errorf("Input %q, got %q, expected %q", elem.input, output, elem.output)
+		}
+		words, err := Split(output)
+		if err != nil {
+			t.Errorf("Input %q, got error %v", elem.input, err)
+			continue
+		}
+		for i, word := range words {
+			if expected := GlobQuote(elem.input[i]); word != expected {
+				t.Errorf("Input %q, word %d split as %q, expected %q", elem.input, i, word, expected)
+			}
+		}
+	}
+}
+
+func TestJoinAny(t *testing.T) {
+	var nilPtr *net.IPAddr
+	args := []any{"a b", []byte("c'd"), nil, nilPtr, 42, -1.5, true, 90 * time.Second, net.ParseIP("::1"), errors.New("oops!")}
+	expected := `'a b' c\'d '' '' 42 -1.5 true 1m30s ::1 oops\!`
+	if output := JoinAny(args...); output != expected {
+		t.Errorf("Input %v, got %q, expected %q", args, output, expected)
+	}
+	if output := QuoteValue(uint8(7)); output != "7" {
+		t.Errorf("got %q for uint8(7)", output)
+	}
+}
+
 var simpleJoinTest = []struct {
 	input  []string
 	output string
@@ -29,3 +156,98 @@ var simpleJoinTest = []struct {
 	{[]string{"$some_ot~her_)spe!cial_*_characters"}, "\\$some_ot~her_\\)spe\\!cial_\\*_characters"},
 	{[]string{"' "}, "\\'' '"},
 }
+
+var printfStyleTest = []struct {
+	input   string
+	history bool
+	output  string
+}{
+	{"", false, "''"},
+	{"hello world", false, `hello\ world`},
+	{"a=b-c%d@e+f.g/h:i", false, "a=b-c%d@e+f.g/h:i"},
+	{"!\"$&'()*,;<>?[\\]^`{|}", false, "\\!\\\"\\$\\&\\'\\(\\)\\*\\,\\;\\<\\>\\?\\[\\\\\\]\\^\\`\\{\\|\\}"},
+	{"#a#b", false, `\#a#b`},
+	{"~a~b:~c=~d", false, `\~a~b:\~c=\~d`},
+	{"caf\u00e9\u00a0x", false, "caf\u00e9\u00a0x"},
+	{"a\tb\n", false, `$'a\tb\n'`},
+	{"\a\b\f\r\v\x1b\x7f", false, `$'\a\b\f\r\v\E\177'`},
+	{"it's \"$HOME\"\\\n", false, `$'it\'s "$HOME"\\\n'`},
+	{"\u0085\u2028\xff", false, `$'\302\205\342\200\250\377'`},
+	{"\u00e9\x01", false, "$'\u00e9\\001'"},
+	{"a!b", true, `a\!b`},
+	{"a!\n", true, `$'a\041\n'`},
+}
+
+var shortestStyleTest = []struct {
+	input   string
+	history bool
+	output  string
+}{
+	{"", false, "''"},
+	{"plain", false, "plain"},
+	{"a b", false, `a\ b`},
+	{"a b c", false, `a\ b\ c`},
+	{"a b c d", false, `'a b c d'`},
+	{"~/a b", false, `\~/a\ b`},
+	{"a\nb", false, "'a\nb'"},
+	{"it's", false, `it\'s`},
+	{"it's a test", false, `"it's a test"`},
+	{"don't do $x", false, `"don't do \$x"`},
+	{`C:\dir\`, false, `C:\\dir\\`},
+	{`it's C:\a b\`, false, `"it's C:\a b\\"`},
+	{"hi! it's", false, `"hi! it's"`},
+	{"hi! it's me", true, `hi\!\ it\'s\ me`},
+	{"a b c d!", true, `'a b c d'\!`},
+}
+
+var asciiOnlyTest = []struct {
+	input  []string
+	style  QuoteStyle
+	output string
+}{
+	{[]string{"a b", "", "c"}, DefaultStyle, "'a b' '' c"},
+	{[]string{"caf\u00e9", "it's"}, DefaultStyle, `$'caf\u00e9' it\'s`},
+	{[]string{"a\tb", "\U0001F600!"}, DefaultStyle, `$'a\tb' $'\U0001f600!'`},
+	{[]string{"\xff\xc3", "\u0085'"}, ShortestStyle, `$'\xff\xc3' $'\u0085\''`},
+	{[]string{"\u00e9a", "a b"}, PrintfStyle, `$'\u00e9a' a\ b`},
+}
+
+var runeEscapesTest = []struct {
+	input  string
+	opts   QuoteOptions
+	output string
+}{
+	{"caf\u00e9 \x1b[2J", QuoteOptions{}, "'caf\u00e9 \x1b[2J'"},
+	{"caf\u00e9 \x1b[2J", QuoteOptions{EscapeNonPrintable: true}, "$'caf\u00e9 \\E[2J'"},
+	{"caf\u00e9 \x1b[2J", QuoteOptions{EscapeNonPrintable: true, ASCIIOnly: true}, `$'caf\u00e9 \E[2J'`},
+	{"caf\u00e9", QuoteOptions{EscapeNonPrintable: true}, "caf\u00e9"},
+	{"\x00\x7f\u0085\U000E0000\xff", QuoteOptions{Style: PrintfStyle}, `$'\000\177\302\205\363\240\200\200\377'`},
+	{"\x00\x7f\u0085\U000E0000\xff", QuoteOptions{Style: PrintfStyle, HexEscapes: true}, `$'\x00\x7f\u0085\U000e0000\xff'`},
+	{"\u00e9\u0085", QuoteOptions{Style: ShortestStyle, EscapeNonPrintable: true, HexEscapes: true}, "$'\u00e9\\u0085'"},
+	{"\u00e9\u0085", QuoteOptions{Style: ShortestStyle, ASCIIOnly: true, HexEscapes: true}, `$'\u00e9\u0085'`},
+}
+
+var assignmentsTest = []struct {
+	input  []string
+	mode   AssignmentMode
+	style  QuoteStyle
+	output string
+}{
+	{[]string{"FOO=a b", "cmd"}, NoAssignments, DefaultStyle, "'FOO=a b' cmd"},
+	{[]string{"FOO=a b", "_x1=", "cmd", "BAR=c d"}, LeadingAssignments, DefaultStyle, "FOO='a b' _x1= cmd 'BAR=c d'"},
+	{[]string{"FOO=a b", "cmd", "BAR=c d"}, AllAssignments, DefaultStyle, "FOO='a b' cmd BAR='c d'"},
+	{[]string{"1X=a b", "=a", "X"}, AllAssignments, DefaultStyle, "'1X=a b' =a X"},
+	{[]string{"P=~/bin:~x", "Q=~", "R=it's"}, LeadingAssignments, DefaultStyle, `P='~/bin:~x' Q=\~ R=it\'s`},
+	{[]string{"P=a:~x b"}, LeadingAssignments, PrintfStyle, `P=a:\~x\ b`},
+}
+
+var escapeGlobsTest = []struct {
+	input  []string
+	style  QuoteStyle
+	output string
+}{
+	{[]string{"ls", "-l", "plain"}, DefaultStyle, "ls -l plain"},
+	{[]string{"find", "*.go", "a]", "x\\y"}, DefaultStyle, `find \\\*.go a\\] x\\\\y`},
+	{[]string{"P=[a] b", "rm", "a b?"}, DefaultStyle, `P='\[a\] b' rm 'a b\?'`},
+	{[]string{"*.go", "a]"}, PrintfStyle, `\\\*.go a\\\]`},
+}
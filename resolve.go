@@ -0,0 +1,49 @@
+package shellquote
+
+import "os/exec"
+
+// Resolver returns the path of the executable for the named program, like
+// exec.LookPath does.
+type Resolver func(name string) (string, error)
+
+// ResolveError is returned by SplitAndResolve when the program can't be
+// resolved.
+type ResolveError struct {
+	Name string
+	Err  error
+}
+
+func (e *ResolveError) Error() string {
+	return "Cannot resolve " + e.Name + ": " + e.Err.Error()
+}
+
+func (e *ResolveError) Unwrap() error {
+	return e.Err
+}
+
+// SplitAndResolve splits input according to opts and looks up the program
+// named by the first word with resolve, or exec.LookPath if resolve is nil.
+// It returns the resolved path along with all words, the first one
+// unchanged.
+//
+// If input can't be split, the error from SplitWithOptions is returned. If
+// it has no words, the error is EmptyCommandError, and if the program can't
+// be found, a *ResolveError wrapping the error from resolve, which for
+// exec.LookPath wraps exec.ErrNotFound if the program doesn't exist.
+func SplitAndResolve(input string, opts *SplitOptions, resolve Resolver) (path string, argv []string, err error) {
+	argv, err = SplitWithOptions(input, opts)
+	if err != nil {
+		return "", argv, err
+	}
+	if len(argv) == 0 {
+		return "", argv, EmptyCommandError
+	}
+	if resolve == nil {
+		resolve = exec.LookPath
+	}
+	path, err = resolve(argv[0])
+	if err != nil {
+		return "", argv, &ResolveError{Name: argv[0], Err: err}
+	}
+	return path, argv, nil
+}
@@ -0,0 +1,48 @@
+package shellquote
+
+import (
+	"errors"
+	"os/exec"
+	"reflect"
+	"testing"
+)
+
+func TestSplitAndResolve(t *testing.T) {
+	resolve := func(name string) (string, error) {
+		if name == "my tool" {
+			return "/opt/bin/my tool", nil
+		}
+		return "", exec.ErrNotFound
+	}
+
+	path, argv, err := SplitAndResolve("'my tool' -x 'a b'", nil, resolve)
+	if err != nil || path != "/opt/bin/my tool" || !reflect.DeepEqual(argv, []string{"my tool", "-x", "a b"}) {
+		t.Errorf("got %q %q (%v)", path, argv, err)
+	}
+
+	_, _, err = SplitAndResolve("other -x", nil, resolve)
+	var resolveErr *ResolveError
+	if !errors.As(err, &resolveErr) || resolveErr.Name != "other" || !errors.Is(err, exec.ErrNotFound) {
+		t.Errorf("got error %v, expected a *ResolveError for other", err)
+	}
+
+	if _, _, err = SplitAndResolve("'my tool", nil, resolve); !errors.Is(err, UnterminatedSingleQuoteError) {
+		t.Errorf("got error %v, expected UnterminatedSingleQuoteError", err)
+	}
+	if _, _, err = SplitAndResolve(" ", nil, resolve); !errors.Is(err, EmptyCommandError) {
+		t.Errorf("got error %v, expected EmptyCommandError", err)
+	}
+}
+
+func TestSplitAndResolveLookPath(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not found")
+	}
+	if path, _, err := SplitAndResolve("sh -c 'exit 0'", nil, nil); err != nil || path != sh {
+		t.Errorf("got %q (%v), expected %q", path, err, sh)
+	}
+	if _, _, err := SplitAndResolve("no-such-program-here", nil, nil); !errors.Is(err, exec.ErrNotFound) {
+		t.Errorf("got error %v, expected exec.ErrNotFound", err)
+	}
+}
@@ -0,0 +1,206 @@
+package shellquote
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Command is a logical line of a script as returned by SplitScript.
+type Command struct {
+	Args []string
+	// Line is the 1-based number of the line the command starts on.
+	Line int
+	// Text is the raw text of the command, including any continuation
+	// lines and the final newline.
+	Text string
+}
+
+// SplitScript reads a script from r and splits each logical line of it
+// according to opts. Lines ending in a backslash are joined with the next
+// one, and quoted strings may span several lines. Blank lines and lines
+// that only hold a comment are skipped. If opts is nil, the default split
+// options with # as CommentChar are used; otherwise opts is used as it is,
+// so comments are only recognized if it sets a CommentChar.
+//
+// Reading stops at the first line that cannot be split; the commands found
+// up to that point are returned with an error that mentions the line and
+// wraps the split error.
+func SplitScript(r io.Reader, opts *SplitOptions) ([]Command, error) {
+	if opts == nil {
+		opts = DefaultSplitOptions()
+		opts.CommentChar = '#'
+	}
+
+	var commands []Command
+	br := bufio.NewReader(r)
+	line := 1
+	for {
+		args, raw, err := ReadCommand(br, opts)
+		if err == io.EOF {
+			return commands, nil
+		} else if err != nil {
+			return commands, fmt.Errorf("line %d: %w", line, err)
+		}
+		if len(args) > 0 {
+			commands = append(commands, Command{Args: args, Line: line, Text: raw})
+		}
+		line += strings.Count(raw, "\n")
+	}
+}
+
+// ReadCommand reads lines from r until they form a complete command and
+// splits it according to opts. A line ending in a backslash, or in the
+// middle of a quoted string, is continued on the next line, even if opts
+// sets LiteralTrailingEscape, which then only applies to the end of the
+// input. It returns the words along with the raw text consumed, including
+// newlines. A blank line yields no words.
+//
+// At the end of the input ReadCommand returns io.EOF if nothing was read,
+// and otherwise splits what it has, so that an unterminated quoted string
+// is reported as a split error.
+func ReadCommand(r *bufio.Reader, opts *SplitOptions) (args []string, raw string, err error) {
+	lineOpts := opts
+	if opts != nil && opts.LiteralTrailingEscape {
+		lineOpts = opts.Clone()
+		lineOpts.LiteralTrailingEscape = false
+	}
+	var text strings.Builder
+	for {
+		line, readErr := r.ReadString('\n')
+		text.WriteString(line)
+		if readErr != nil && readErr != io.EOF {
+			return nil, text.String(), readErr
+		}
+		if readErr == io.EOF && text.Len() == 0 {
+			return nil, "", io.EOF
+		}
+
+		raw = text.String()
+		if readErr == io.EOF {
+			args, err = SplitWithOptions(strings.TrimSuffix(raw, "\n"), opts)
+			return args, raw, err
+		}
+		args, err = SplitWithOptions(strings.TrimSuffix(raw, "\n"), lineOpts)
+		if err == nil || !incomplete(err) {
+			return args, raw, err
+		}
+	}
+}
+
+// incomplete reports whether err indicates that the input ended in the
+// middle of a quoted string or right after an escape character, so that
+// reading another line could complete it.
+func incomplete(err error) bool {
+	return errors.Is(err, UnterminatedSingleQuoteError) || errors.Is(err, UnterminatedDoubleQuoteError) || errors.Is(err, UnterminatedEscapeError)
+}
+
+// ScriptTarget selects the context the output of JoinScriptWithOptions is
+// going to be embedded in.
+type ScriptTarget int
+
+const (
+	// PlainScript is a script file of its own.
+	PlainScript ScriptTarget = iota
+	// HeredocScript is the body of a here-document with a quoted
+	// delimiter, as in cat <<'EOF'. No line of the script will consist of
+	// the delimiter alone.
+	HeredocScript
+	// SingleQuotedScript is the inside of a single-quoted string, as in
+	// bash -c '...'. Every single-quote in the script is replaced by '\'',
+	// which ends the string, adds an escaped quote and starts it again.
+	SingleQuotedScript
+)
+
+// ScriptOptions configures JoinScriptWithOptions.
+type ScriptOptions struct {
+	Target ScriptTarget
+	// Delimiter is the here-document delimiter for HeredocScript. The
+	// default is EOF.
+	Delimiter string
+	// Quote configures the quoting of each word. The default quotes like
+	// Join.
+	Quote *QuoteOptions
+}
+
+func DefaultScriptOptions() *ScriptOptions {
+	return &ScriptOptions{Delimiter: "EOF"}
+}
+
+// JoinScript quotes each command like Join and puts each on a line of its
+// own. The script ends in a newline unless there are no commands.
+func JoinScript(commands [][]string) string {
+	return JoinScriptWithOptions(commands, nil)
+}
+
+// JoinScriptWithOptions is JoinScript with the quoting chosen to survive
+// embedding the script in the context selected by opts.
+func JoinScriptWithOptions(commands [][]string, opts *ScriptOptions) string {
+	if opts == nil {
+		opts = DefaultScriptOptions()
+	}
+	quoteOpts := opts.Quote
+	if quoteOpts == nil {
+		quoteOpts = DefaultQuoteOptions()
+	}
+	delimiter := opts.Delimiter
+	if delimiter == "" {
+		delimiter = "EOF"
+	}
+
+	var buf strings.Builder
+	for _, args := range commands {
+		var line string
+		if opts.Target == HeredocScript {
+			line = joinHeredocSafe(args, delimiter, quoteOpts)
+		} else {
+			line = JoinWithOptions(args, quoteOpts)
+		}
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	if opts.Target == SingleQuotedScript {
+		return strings.ReplaceAll(buf.String(), "'", `'\''`)
+	}
+	return buf.String()
+}
+
+// joinHeredocSafe joins args like JoinWithOptions, making sure that no line
+// of the result is the given here-document delimiter. Newlines in words are
+// written double-quoted between single-quoted parts, so every line they
+// start or end has a quote character next to the line break.
+func joinHeredocSafe(args []string, delimiter string, opts *QuoteOptions) string {
+	line := JoinWithOptions(args, opts)
+	if !hasLine(line, delimiter) {
+		return line
+	}
+	if !strings.Contains(line, "\n") {
+		// an empty string in front doesn't change the first word
+		return "''" + line
+	}
+	words := make([]string, len(args))
+	for i, arg := range args {
+		if !strings.Contains(arg, "\n") {
+			words[i] = QuoteWithOptions(arg, opts)
+			continue
+		}
+		parts := strings.Split(arg, "\n")
+		for j, part := range parts {
+			parts[j] = "'" + strings.ReplaceAll(part, "'", `'\''`) + "'"
+		}
+		words[i] = strings.Join(parts, "\"\n\"")
+	}
+	return strings.Join(words, " ")
+}
+
+// hasLine reports whether one of the lines of s is line.
+func hasLine(s, line string) bool {
+	for _, l := range strings.Split(s, "\n") {
+		if l == line {
+			return true
+		}
+	}
+	return false
+}
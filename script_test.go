@@ -0,0 +1,146 @@
+package shellquote
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitScript(t *testing.T) {
+	script := `#!/bin/sh
+# install dependencies
+apt-get install -y \
+    curl \
+    'git lfs'
+
+echo "multi
+line" # trailing comment
+  # indented comment
+ls#not-a-comment`
+	commands, err := SplitScript(strings.NewReader(script), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Command{
+		{Args: []string{"apt-get", "install", "-y", "curl", "git lfs"}, Line: 3, Text: "apt-get install -y \\\n    curl \\\n    'git lfs'\n"},
+		{Args: []string{"echo", "multi\nline"}, Line: 7, Text: "echo \"multi\nline\" # trailing comment\n"},
+		{Args: []string{"ls#not-a-comment"}, Line: 10, Text: "ls#not-a-comment"},
+	}
+	if !reflect.DeepEqual(commands, expected) {
+		t.Errorf("got %+v, expected %+v", commands, expected)
+	}
+}
+
+func TestSplitScriptError(t *testing.T) {
+	commands, err := SplitScript(strings.NewReader("echo ok\necho 'oops\nmore\n"), nil)
+	if len(commands) != 1 || !errors.Is(err, UnterminatedSingleQuoteError) {
+		t.Fatalf("got %+v and error %v", commands, err)
+	}
+	if !strings.HasPrefix(err.Error(), "line 2: ") {
+		t.Errorf("got error %q, expected it to mention line 2", err)
+	}
+}
+
+func TestSplitScriptOptions(t *testing.T) {
+	opts := DefaultSplitOptions()
+	opts.LiteralTrailingEscape = true
+	commands, err := SplitScript(strings.NewReader("ls a \\\n  b #c\ncd C:\\"), opts)
+	expected := []Command{
+		{Args: []string{"ls", "a", "b", "#c"}, Line: 1, Text: "ls a \\\n  b #c\n"},
+		{Args: []string{"cd", "C:\\"}, Line: 3, Text: "cd C:\\"},
+	}
+	if err != nil || !reflect.DeepEqual(commands, expected) {
+		t.Errorf("got %+v (%v), expected %+v", commands, err, expected)
+	}
+}
+
+func TestCommentChar(t *testing.T) {
+	opts := DefaultSplitOptions()
+	opts.CommentChar = '#'
+	output, err := SplitWithOptions("a #b c\nd e#f '#g' # h", opts)
+	if expected := []string{"a", "d", "e#f", "#g"}; err != nil || !reflect.DeepEqual(output, expected) {
+		t.Errorf("got %q (%v), expected %q", output, err, expected)
+	}
+}
+
+func TestReadCommand(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("get 'a\nb' \\\n c\n\nput x\nbad \"y"))
+	for _, elem := range []struct {
+		args []string
+		raw  string
+		err  error
+	}{
+		{[]string{"get", "a\nb", "c"}, "get 'a\nb' \\\n c\n", nil},
+		{[]string{}, "\n", nil},
+		{[]string{"put", "x"}, "put x\n", nil},
+		{[]string{"bad"}, "bad \"y", UnterminatedDoubleQuoteError},
+		{nil, "", io.EOF},
+	} {
+		args, raw, err := ReadCommand(r, nil)
+		if !reflect.DeepEqual(args, elem.args) || raw != elem.raw || !errors.Is(err, elem.err) {
+			t.Errorf("got %q, %q, %v, expected %q, %q, %v", args, raw, err, elem.args, elem.raw, elem.err)
+		}
+	}
+}
+
+func TestJoinScript(t *testing.T) {
+	for _, elem := range joinScriptTest {
+		output := JoinScriptWithOptions(elem.input, &ScriptOptions{Target: elem.target})
+		if output != elem.output {
+			t.Errorf("Input %q, got %q, expected %q", elem.input, output, elem.output)
+		}
+	}
+	if output := JoinScript([][]string{{"a", "b c"}, {"d"}}); output != "a 'b c'\nd\n" {
+		t.Errorf("got %q", output)
+	}
+}
+
+func TestJoinScriptAgainstShell(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not found")
+	}
+	commands := [][]string{
+		{"printf", "%s|", "it's", "a\nEOF\nb", "$HOME"},
+		{"printf", "%s|", "EOF"},
+	}
+	var expected strings.Builder
+	for _, args := range commands {
+		for _, arg := range args[2:] {
+			expected.WriteString(arg + "|")
+		}
+	}
+	for _, target := range []ScriptTarget{PlainScript, HeredocScript, SingleQuotedScript} {
+		script := JoinScriptWithOptions(commands, &ScriptOptions{Target: target})
+		var cmd *exec.Cmd
+		switch target {
+		case HeredocScript:
+			cmd = exec.Command(sh, "-c", "sh <<'EOF'\n"+script+"EOF\n")
+		case SingleQuotedScript:
+			cmd = exec.Command(sh, "-c", "sh -c '"+script+"'")
+		default:
+			cmd = exec.Command(sh, "-c", script)
+		}
+		output, err := cmd.Output()
+		if err != nil || string(output) != expected.String() {
+			t.Errorf("Target %d, script %q gives %q (%v), expected %q", target, script, output, err, expected.String())
+		}
+	}
+}
+
+var joinScriptTest = []struct {
+	input  [][]string
+	target ScriptTarget
+	output string
+}{
+	{nil, PlainScript, ""},
+	{[][]string{{"echo", "a\nEOF"}}, PlainScript, "echo 'a\nEOF'\n"},
+	{[][]string{{"echo", "a\nEOF"}, {"EOF"}}, HeredocScript, "echo 'a\nEOF'\n''EOF\n"},
+	{[][]string{{"echo", "a\nEOF\nb", "c"}}, HeredocScript, "echo 'a'\"\n\"'EOF'\"\n\"'b' c\n"},
+	{[][]string{{"echo", "a\nb"}}, HeredocScript, "echo 'a\nb'\n"},
+	{[][]string{{"echo", "it's"}}, SingleQuotedScript, "echo it\\'\\''s\n"},
+}
@@ -0,0 +1,106 @@
+// Package shellquotetest provides test helpers for code that splits and
+// quotes command lines with shellquote. On failure, they report the words
+// one per line, both as Go strings and quoted for the shell, so that
+// differences in whitespace, quotes and escapes are easy to spot.
+package shellquotetest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	shellquote "github.com/nmeilick/go-shellquote"
+)
+
+// RequireSplitsTo fails the test immediately unless input splits into want
+// with the default split options.
+func RequireSplitsTo(t testing.TB, input string, want []string) {
+	t.Helper()
+	RequireSplitsToWithOptions(t, input, nil, want)
+}
+
+// RequireSplitsToWithOptions fails the test immediately unless input splits
+// into want with opts.
+func RequireSplitsToWithOptions(t testing.TB, input string, opts *shellquote.SplitOptions, want []string) {
+	t.Helper()
+	got, err := shellquote.SplitWithOptions(input, opts)
+	if err != nil {
+		t.Fatalf("Splitting %q: %v", input, err)
+		return
+	}
+	if diff := Diff(got, want); diff != "" {
+		t.Fatalf("Splitting %q gave %d words, expected %d (- got, + expected):\n%s", input, len(got), len(want), diff)
+	}
+}
+
+// RequireEquivalent fails the test immediately unless the command lines a
+// and b split into the same words with the default split options.
+func RequireEquivalent(t testing.TB, a, b string) {
+	t.Helper()
+	RequireEquivalentWithOptions(t, a, b, nil)
+}
+
+// RequireEquivalentWithOptions fails the test immediately unless the
+// command lines a and b split into the same words with opts.
+func RequireEquivalentWithOptions(t testing.TB, a, b string, opts *shellquote.SplitOptions) {
+	t.Helper()
+	aWords, err := shellquote.SplitWithOptions(a, opts)
+	if err != nil {
+		t.Fatalf("Splitting %q: %v", a, err)
+		return
+	}
+	bWords, err := shellquote.SplitWithOptions(b, opts)
+	if err != nil {
+		t.Fatalf("Splitting %q: %v", b, err)
+		return
+	}
+	if diff := Diff(aWords, bWords); diff != "" {
+		t.Fatalf("%q and %q split differently (- first, + second):\n%s", a, b, diff)
+	}
+}
+
+// Diff returns a word-by-word comparison of got and want, or "" if they are
+// equal. Each word is shown with its index, as a Go string and quoted with
+// shellquote.Quote. Words only in got are marked with -, words only in
+// want with +, and differing words give one line of each.
+func Diff(got, want []string) string {
+	if equalWords(got, want) {
+		return ""
+	}
+	width := 0
+	for _, words := range [][]string{got, want} {
+		for _, word := range words {
+			width = max(width, len(fmt.Sprintf("%q", word)))
+		}
+	}
+	var buf strings.Builder
+	line := func(mark byte, i int, word string) {
+		fmt.Fprintf(&buf, "%c [%d] %-*q  %s\n", mark, i, width, word, shellquote.Quote(word))
+	}
+	for i := 0; i < max(len(got), len(want)); i++ {
+		switch {
+		case i >= len(want):
+			line('-', i, got[i])
+		case i >= len(got):
+			line('+', i, want[i])
+		case got[i] != want[i]:
+			line('-', i, got[i])
+			line('+', i, want[i])
+		default:
+			line(' ', i, got[i])
+		}
+	}
+	return buf.String()
+}
+
+func equalWords(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
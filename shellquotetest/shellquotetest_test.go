@@ -0,0 +1,67 @@
+package shellquotetest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	shellquote "github.com/nmeilick/go-shellquote"
+)
+
+// recorder is a testing.TB recording failures instead of stopping the test.
+type recorder struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func TestRequireSplitsTo(t *testing.T) {
+	RequireSplitsTo(t, `a 'b c' d\ e`, []string{"a", "b c", "d e"})
+	RequireSplitsToWithOptions(t, `a "b"`, shellquote.NoEscapeSplitOptions(), []string{"a", "b"})
+
+	r := &recorder{TB: t}
+	RequireSplitsTo(r, `a 'b  c'`, []string{"a", "b c", ""})
+	expected := "Splitting \"a 'b  c'\" gave 2 words, expected 3 (- got, + expected):\n" +
+		"  [0] \"a\"     a\n" +
+		"- [1] \"b  c\"  'b  c'\n" +
+		"+ [1] \"b c\"   'b c'\n" +
+		"+ [2] \"\"      ''\n"
+	if len(r.failures) != 1 || r.failures[0] != expected {
+		t.Errorf("got failures %q, expected %q", r.failures, expected)
+	}
+
+	r = &recorder{TB: t}
+	RequireSplitsTo(r, `a 'b`, nil)
+	if len(r.failures) != 1 || !strings.Contains(r.failures[0], shellquote.UnterminatedSingleQuoteError.Error()) {
+		t.Errorf("got failures %q for an unterminated quote", r.failures)
+	}
+}
+
+func TestRequireEquivalent(t *testing.T) {
+	RequireEquivalent(t, `echo 'a b' "c"`, `echo a\ b c`)
+
+	r := &recorder{TB: t}
+	RequireEquivalent(r, `echo a b`, `echo 'a b'`)
+	expected := "\"echo a b\" and \"echo 'a b'\" split differently (- first, + second):\n" +
+		"  [0] \"echo\"  echo\n" +
+		"- [1] \"a\"     a\n" +
+		"+ [1] \"a b\"   'a b'\n" +
+		"- [2] \"b\"     b\n"
+	if len(r.failures) != 1 || r.failures[0] != expected {
+		t.Errorf("got failures %q, expected %q", r.failures, expected)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	if diff := Diff([]string{"a"}, []string{"a"}); diff != "" {
+		t.Errorf("got %q for equal words", diff)
+	}
+	if diff := Diff(nil, []string{}); diff != "" {
+		t.Errorf("got %q for nil and empty words", diff)
+	}
+}
@@ -0,0 +1,37 @@
+package shellquote
+
+import "strings"
+
+// sshTokenChars are the characters following the % of the tokens OpenSSH
+// expands in ProxyCommand, LocalCommand and Match exec values.
+const sshTokenChars = "%CdhijkLlnprTu"
+
+// sshTokenLen returns the length of the OpenSSH token s starts with, or 0
+// if there is none.
+func sshTokenLen(s string) int {
+	if len(s) < 2 || s[0] != '%' || strings.IndexByte(sshTokenChars, s[1]) < 0 {
+		return 0
+	}
+	return 2
+}
+
+// escapeSSHPercents doubles every % in word that doesn't start an OpenSSH
+// token.
+func escapeSSHPercents(word string) string {
+	if strings.IndexByte(word, '%') < 0 {
+		return word
+	}
+	var buf strings.Builder
+	for i := 0; i < len(word); i++ {
+		if n := sshTokenLen(word[i:]); n > 0 {
+			buf.WriteString(word[i : i+n])
+			i += n - 1
+			continue
+		}
+		if word[i] == '%' {
+			buf.WriteByte('%')
+		}
+		buf.WriteByte(word[i])
+	}
+	return buf.String()
+}
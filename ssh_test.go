@@ -0,0 +1,59 @@
+package shellquote
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSplitSSHTokens(t *testing.T) {
+	opts := DefaultSplitOptions()
+	opts.SSHTokens = true
+	opts.DetailedErrors = true
+	for _, elem := range splitSSHTokensTest {
+		output, err := SplitWithOptions(elem.input, opts)
+		if elem.offset >= 0 {
+			var serr *SplitError
+			if !errors.As(err, &serr) || serr.Kind != InvalidSSHToken || serr.Offset != elem.offset {
+				t.Errorf("Input %q, got error %v, expected InvalidSSHToken at offset %d", elem.input, err, elem.offset)
+			}
+		} else if err != nil {
+			t.Errorf("Input %q, got error %v", elem.input, err)
+		} else if !reflect.DeepEqual(output, elem.output) {
+			t.Errorf("Input %q, got %q, expected %q", elem.input, output, elem.output)
+		} else if joined := JoinWithOptions(output, &QuoteOptions{SSHTokens: true}); joined != elem.input {
+			t.Errorf("Input %q, joined back as %q", elem.input, joined)
+		}
+	}
+}
+
+func TestQuoteSSHTokens(t *testing.T) {
+	for _, elem := range quoteSSHTokensTest {
+		output := JoinWithOptions(elem.input, &QuoteOptions{SSHTokens: true})
+		if output != elem.output {
+			t.Errorf("Input %q, got %q, expected %q", elem.input, output, elem.output)
+		}
+	}
+}
+
+var splitSSHTokensTest = []struct {
+	input  string
+	output []string
+	offset int
+}{
+	{"nc %h %p", []string{"nc", "%h", "%p"}, -1},
+	{"ssh -W %h:%p 'jump %r'", []string{"ssh", "-W", "%h:%p", "jump %r"}, -1},
+	{"echo 100%%", []string{"echo", "100%%"}, -1},
+	{"echo 100%", nil, 8},
+	{"nc %h %x", nil, 6},
+	{"'%' %h", nil, 1},
+}
+
+var quoteSSHTokensTest = []struct {
+	input  []string
+	output string
+}{
+	{[]string{"nc", "%h", "%p"}, "nc %h %p"},
+	{[]string{"printf", "50% of %n", "%%"}, "printf '50%% of %n' %%"},
+	{[]string{"%", "%x%", "%C"}, "%% %%x%% %C"},
+}
@@ -0,0 +1,144 @@
+package shellquote
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+var UnrepresentableArgumentError = errors.New("Argument can't be passed to the remote shell")
+
+// WindowsShell is the shell an OpenSSH server on Windows runs remote
+// commands with, as configured by its DefaultShell registry value.
+type WindowsShell int
+
+const (
+	// WindowsCmd is cmd.exe, the default.
+	WindowsCmd WindowsShell = iota
+	// WindowsPowerShell is Windows PowerShell 5.1, powershell.exe, which
+	// passes arguments to native programs without escaping the
+	// double-quotes inside them.
+	WindowsPowerShell
+	// Pwsh is PowerShell 7.3 or later, pwsh.exe, with its standard
+	// argument passing.
+	Pwsh
+)
+
+// JoinSSHWindows returns the command to give ssh for running args on a
+// Windows host whose OpenSSH server uses shell. The program named by the
+// first argument receives the arguments as split by the Microsoft C
+// runtime and CommandLineToArgvW.
+//
+// Unlike on POSIX hosts, the command isn't read by sh. With WindowsCmd,
+// each argument is quoted for the C runtime, and then every character
+// cmd.exe interprets, including % and the double-quotes, is escaped with a
+// caret. With the PowerShell shells, the command is run with the call
+// operator & and single-quoted arguments, and the whole is quoted for the
+// C runtime once more, as the PowerShell executable splits its command
+// line before running it. For WindowsPowerShell, double-quotes and
+// trailing backslashes are escaped in advance, and empty arguments are
+// passed as "". Pwsh in its default Windows mode still passes arguments
+// like WindowsPowerShell when starting batch files and a few programs like
+// cmd.exe and msiexec.exe.
+//
+// Arguments that can't be passed return an error wrapping
+// UnrepresentableArgumentError: those containing a NUL byte, a newline or
+// carriage return for WindowsCmd, or both whitespace and a double-quote
+// for WindowsPowerShell.
+//
+// ssh sends the command unchanged if it is given as a single argument
+// following the destination. If ssh itself is started by a shell, the
+// command must be quoted for that shell as well, as with Join.
+func JoinSSHWindows(args []string, shell WindowsShell) (string, error) {
+	if len(args) == 0 {
+		return "", nil
+	}
+	for _, word := range args {
+		if strings.IndexByte(word, 0) >= 0 || shell == WindowsCmd && strings.ContainsAny(word, "\r\n") {
+			return "", fmt.Errorf("%w: %q", UnrepresentableArgumentError, word)
+		}
+	}
+
+	var buf, arg bytes.Buffer
+	if shell == WindowsCmd {
+		for i, word := range args {
+			if i != 0 {
+				buf.WriteByte(' ')
+			}
+			arg.Reset()
+			quoteMSVCRT(word, &arg)
+			for _, c := range arg.Bytes() {
+				if c == '%' || strings.IndexByte(batchSpecialChars, c) >= 0 {
+					buf.WriteByte('^')
+				}
+				buf.WriteByte(c)
+			}
+		}
+		return buf.String(), nil
+	}
+
+	arg.WriteByte('&')
+	for _, word := range args {
+		if shell == WindowsPowerShell {
+			var err error
+			if word, err = legacyNativeArgument(word); err != nil {
+				return "", err
+			}
+		}
+		arg.WriteByte(' ')
+		quotePowerShell(word, &arg)
+	}
+	quoteMSVCRT(arg.String(), &buf)
+	return buf.String(), nil
+}
+
+// powerShellSingleQuotes are the characters PowerShell accepts as
+// single-quotes.
+const powerShellSingleQuotes = "'‘’‚‛"
+
+// quotePowerShell writes word as a single-quoted PowerShell string, in
+// which the single-quotes are doubled.
+func quotePowerShell(word string, buf *bytes.Buffer) {
+	buf.WriteByte('\'')
+	for _, r := range word {
+		if strings.ContainsRune(powerShellSingleQuotes, r) {
+			buf.WriteRune(r)
+		}
+		buf.WriteRune(r)
+	}
+	buf.WriteByte('\'')
+}
+
+// legacyNativeArgument escapes word so that Windows PowerShell passes it to
+// a native program intact. PowerShell puts double-quotes around arguments
+// with whitespace outside of double-quotes, and leaves them unchanged
+// otherwise.
+func legacyNativeArgument(word string) (string, error) {
+	space := strings.IndexFunc(word, unicode.IsSpace) >= 0
+	switch {
+	case word == "":
+		return `""`, nil
+	case space && strings.IndexByte(word, '"') >= 0:
+		return "", fmt.Errorf("%w: %q", UnrepresentableArgumentError, word)
+	case space:
+		trailing := len(word) - len(strings.TrimRight(word, `\`))
+		return word + strings.Repeat(`\`, trailing), nil
+	}
+	var buf strings.Builder
+	slashes := 0
+	for i := 0; i < len(word); i++ {
+		switch word[i] {
+		case '\\':
+			slashes++
+		case '"':
+			buf.WriteString(strings.Repeat(`\`, slashes+1))
+			slashes = 0
+		default:
+			slashes = 0
+		}
+		buf.WriteByte(word[i])
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,39 @@
+package shellquote
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestJoinSSHWindows(t *testing.T) {
+	for _, elem := range joinSSHWindowsTest {
+		output, err := JoinSSHWindows(elem.args, elem.shell)
+		if elem.err {
+			if !errors.Is(err, UnrepresentableArgumentError) {
+				t.Errorf("Input %q, got error %v, expected UnrepresentableArgumentError", elem.args, err)
+			}
+		} else if err != nil {
+			t.Errorf("Input %q, got error %v", elem.args, err)
+		} else if output != elem.output {
+			t.Errorf("Input %q, got %q, expected %q", elem.args, output, elem.output)
+		}
+	}
+}
+
+var joinSSHWindowsTest = []struct {
+	args   []string
+	shell  WindowsShell
+	output string
+	err    bool
+}{
+	{nil, WindowsCmd, "", false},
+	{[]string{"git", "status"}, WindowsCmd, `git status`, false},
+	{[]string{`C:\Program Files\x.exe`, "a&b", "50%", `say "hi"`, ""}, WindowsCmd, `^"C:\Program Files\x.exe^" a^&b 50^% ^"say \^"hi\^"^" ^"^"`, false},
+	{[]string{"echo", "a\nb"}, WindowsCmd, "", true},
+	{[]string{"echo", "a\x00b"}, Pwsh, "", true},
+	{[]string{"git", "it's", ""}, Pwsh, `"& 'git' 'it''s' ''"`, false},
+	{[]string{"echo", `a"b`, "x\u2019y"}, Pwsh, `"& 'echo' 'a\"b' 'x` + "\u2019\u2019" + `y'"`, false},
+	{[]string{"echo", `a"b`, "", `C:\dir name\`}, WindowsPowerShell, `"& 'echo' 'a\\\"b' '\"\"' 'C:\dir name\\'"`, false},
+	{[]string{"echo", "a\nb"}, WindowsPowerShell, "\"& 'echo' 'a\nb'\"", false},
+	{[]string{"echo", `a "b`}, WindowsPowerShell, "", true},
+}
@@ -0,0 +1,95 @@
+package shellquote
+
+import "strings"
+
+// Stats summarizes the words of a command, as returned by SplitStats.
+type Stats struct {
+	Words int
+	// LongestWord is the length of the longest word in bytes.
+	LongestWord int
+	// Quoted is the number of words with quoted parts. SingleQuoted,
+	// DoubleQuoted and ANSICQuoted are the number of words with parts
+	// quoted in the respective way, so a word like 'a'"b" counts towards
+	// both of the first two.
+	Quoted, SingleQuoted, DoubleQuoted, ANSICQuoted int
+	// Escaped is the number of words with escape sequences, and Escapes
+	// the total number of escape sequences, inside or outside of quotes.
+	Escaped, Escapes int
+	// Operators reports whether the input contains unquoted control or
+	// redirection operators like ;, &, |, < or >, which Split treats as
+	// part of the words.
+	Operators bool
+	// Substitutions reports whether the input contains command, arithmetic
+	// or process substitutions, and ParameterExpansions whether it contains
+	// parameter expansions.
+	Substitutions, ParameterExpansions bool
+}
+
+// quoteKind is a set of the kinds of quotes used in a word.
+type quoteKind uint8
+
+const (
+	singleQuotes quoteKind = 1 << iota
+	doubleQuotes
+	ansiQuotes
+)
+
+// SplitStats splits input like SplitWithOptions and returns statistics
+// about it instead of the words. The statistics describe the words before
+// any TransformWord is applied, and cover the words found up to an error.
+func SplitStats(input string, opts *SplitOptions) (Stats, error) {
+	var stats Stats
+	s := newSplitter(input, opts, false)
+	s.stats = &stats
+	_, err := s.split(input)
+	return stats, err
+}
+
+// add records a word that used the given kinds of quotes and number of
+// escapes.
+func (st *Stats) add(tok Token, quotes quoteKind, escapes int) {
+	st.Words++
+	if len(tok.Value) > st.LongestWord {
+		st.LongestWord = len(tok.Value)
+	}
+	if quotes != 0 {
+		st.Quoted++
+	}
+	if quotes&singleQuotes != 0 {
+		st.SingleQuoted++
+	}
+	if quotes&doubleQuotes != 0 {
+		st.DoubleQuoted++
+	}
+	if quotes&ansiQuotes != 0 {
+		st.ANSICQuoted++
+	}
+	if escapes > 0 {
+		st.Escaped++
+		st.Escapes += escapes
+	}
+}
+
+// noteStats records the operators and expansions at the start of input,
+// which is inside double-quotes if quoted is set. Operators inside
+// substitutions are ignored.
+func (s *splitter) noteStats(input string, quoted bool) {
+	st := s.stats
+	switch construct := expansionAt(input, quoted); {
+	case construct == "`":
+		st.Substitutions = true
+		s.backquote = !s.backquote
+	case strings.HasSuffix(construct, "("):
+		st.Substitutions = true
+		s.openParen = true
+	case construct != "":
+		st.ParameterExpansions = true
+	case input[0] == '(' && (s.openParen || s.depth > 0):
+		s.depth++
+		s.openParen = false
+	case input[0] == ')' && s.depth > 0:
+		s.depth--
+	case !quoted && s.depth == 0 && !s.backquote && strings.IndexByte(";&|<>()", input[0]) >= 0:
+		st.Operators = true
+	}
+}
@@ -0,0 +1,32 @@
+package shellquote
+
+import (
+	"testing"
+)
+
+func TestSplitStats(t *testing.T) {
+	opts, _ := Preset("bash")
+	for _, elem := range splitStatsTest {
+		stats, err := SplitStats(elem.input, opts)
+		if err != nil {
+			t.Errorf("Input %q, got error %v", elem.input, err)
+		} else if stats != elem.stats {
+			t.Errorf("Input %q, got %+v, expected %+v", elem.input, stats, elem.stats)
+		}
+	}
+}
+
+var splitStatsTest = []struct {
+	input string
+	stats Stats
+}{
+	{"", Stats{}},
+	{"ls -l /tmp", Stats{Words: 3, LongestWord: 4}},
+	{`echo 'a b'"c" "d\"e" f\ g\\ $'\t'`, Stats{Words: 5, LongestWord: 4, Quoted: 3, SingleQuoted: 1, DoubleQuoted: 2, ANSICQuoted: 1, Escaped: 3, Escapes: 4}},
+	{"cat a|grep b>out; x &", Stats{Words: 5, LongestWord: 6, Operators: true}},
+	{`echo $HOME "${x}" '$(no)' "a;b"`, Stats{Words: 5, LongestWord: 5, Quoted: 3, SingleQuoted: 1, DoubleQuoted: 2, ParameterExpansions: true}},
+	{"echo $(date) `id`", Stats{Words: 3, LongestWord: 7, Substitutions: true}},
+	{"x=$(a;(b)) `c|d`", Stats{Words: 2, LongestWord: 10, Substitutions: true}},
+	{"x=$(a) | b", Stats{Words: 3, LongestWord: 6, Operators: true, Substitutions: true}},
+	{"diff <(a) \"$((1+2))\"", Stats{Words: 3, LongestWord: 8, Quoted: 1, DoubleQuoted: 1, Substitutions: true}},
+}
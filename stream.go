@@ -0,0 +1,86 @@
+package shellquote
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+)
+
+var DelimiterInWordError = errors.New("Word contains the record delimiter")
+
+// Decoder reads commands from a stream holding one command line per
+// record, as written by an Encoder.
+type Decoder struct {
+	// Options are the split options used for each record. nil means the
+	// default options.
+	Options *SplitOptions
+	// Delimiter ends each record. With the default, a newline, a record
+	// continues on the next line while a quoted string is open or the line
+	// ends in a backslash, as with ReadCommand. Any other delimiter, like a
+	// NUL byte, simply separates the records.
+	Delimiter byte
+
+	r *bufio.Reader
+}
+
+// NewDecoder returns a Decoder reading newline-delimited records from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{Delimiter: '\n', r: bufio.NewReader(r)}
+}
+
+// Decode reads the next record and stores its words in argv. An empty
+// record gives no words. At the end of the stream, Decode returns io.EOF.
+// A record that can't be split is returned with the split error; decoding
+// may continue with the next record.
+func (d *Decoder) Decode(argv *[]string) error {
+	if d.Delimiter == '\n' {
+		args, _, err := ReadCommand(d.r, d.Options)
+		if err == nil {
+			*argv = args
+		}
+		return err
+	}
+
+	record, err := d.r.ReadString(d.Delimiter)
+	if err == io.EOF && len(record) > 0 {
+		err = nil
+	} else if err != nil {
+		return err
+	}
+	args, err := SplitWithOptions(strings.TrimSuffix(record, string([]byte{d.Delimiter})), d.Options)
+	if err == nil {
+		*argv = args
+	}
+	return err
+}
+
+// Encoder writes commands to a stream, one command line per record, for a
+// Decoder to read.
+type Encoder struct {
+	// Options configure the quoting of each word. nil means quoting like
+	// Join.
+	Options *QuoteOptions
+	// Delimiter is written after each record. The default is a newline.
+	Delimiter byte
+
+	w io.Writer
+}
+
+// NewEncoder returns an Encoder writing newline-delimited records to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{Delimiter: '\n', w: w}
+}
+
+// Encode quotes and joins argv and writes it as a record. A newline inside
+// a word is quoted, which makes a Decoder continue the record on the next
+// line. Any other delimiter can't appear in a word, and Encode returns
+// DelimiterInWordError if it does.
+func (e *Encoder) Encode(argv []string) error {
+	line := JoinWithOptions(argv, e.Options)
+	if e.Delimiter != '\n' && strings.IndexByte(line, e.Delimiter) >= 0 {
+		return DelimiterInWordError
+	}
+	_, err := io.WriteString(e.w, line+string([]byte{e.Delimiter}))
+	return err
+}
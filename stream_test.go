@@ -0,0 +1,70 @@
+package shellquote
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestEncoderDecoder(t *testing.T) {
+	commands := [][]string{
+		{"echo", "hello world"},
+		{},
+		{"printf", "%s\n", "a\nb", "it's"},
+		{"", "x"},
+	}
+	for _, delim := range []byte{'\n', 0} {
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf)
+		enc.Delimiter = delim
+		for _, argv := range commands {
+			if err := enc.Encode(argv); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		dec := NewDecoder(&buf)
+		dec.Delimiter = delim
+		var got [][]string
+		for {
+			var argv []string
+			err := dec.Decode(&argv)
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("Delimiter %q, got error %v", delim, err)
+			}
+			got = append(got, argv)
+		}
+		if !reflect.DeepEqual(got, commands) {
+			t.Errorf("Delimiter %q, got %q, expected %q", delim, got, commands)
+		}
+	}
+}
+
+func TestDecoderRecords(t *testing.T) {
+	dec := NewDecoder(bytes.NewBufferString("a 'b\nc'\nbad 'quote\x00d e"))
+	var argv []string
+	if err := dec.Decode(&argv); err != nil || !reflect.DeepEqual(argv, []string{"a", "b\nc"}) {
+		t.Errorf("got %q (%v)", argv, err)
+	}
+
+	dec.Delimiter = 0
+	if err := dec.Decode(&argv); !errors.Is(err, UnterminatedSingleQuoteError) {
+		t.Errorf("got error %v, expected UnterminatedSingleQuoteError", err)
+	}
+	if err := dec.Decode(&argv); err != nil || !reflect.DeepEqual(argv, []string{"d", "e"}) {
+		t.Errorf("got %q (%v) for the last record", argv, err)
+	}
+	if err := dec.Decode(&argv); err != io.EOF {
+		t.Errorf("got error %v at the end, expected io.EOF", err)
+	}
+
+	enc := NewEncoder(io.Discard)
+	enc.Delimiter = 0
+	if err := enc.Encode([]string{"a\x00b"}); err != DelimiterInWordError {
+		t.Errorf("got error %v, expected DelimiterInWordError", err)
+	}
+}
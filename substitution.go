@@ -0,0 +1,130 @@
+package shellquote
+
+import "strings"
+
+// substitutionStart returns the length of the opening part of the command
+// or process substitution input starts with, or 0 if there is none.
+// Process substitutions aren't recognized inside double-quotes.
+func substitutionStart(input string, quoted bool) int {
+	switch {
+	case strings.HasPrefix(input, "$("):
+		return 2
+	case strings.HasPrefix(input, "`"):
+		return 1
+	case !quoted && (strings.HasPrefix(input, "<(") || strings.HasPrefix(input, ">(")):
+		return 2
+	}
+	return 0
+}
+
+// substitutionLen returns the length of the substitution input starts
+// with if Substitutions is set, and 0 otherwise. If the substitution is
+// unterminated or nested too deeply and CollectErrors is set, the error is
+// recorded and 0 returned, so that it is read as literal text.
+func (s *splitter) substitutionLen(input string, quoted bool) (int, error) {
+	if !s.opts.Substitutions {
+		return 0, nil
+	}
+	n := substitutionStart(input, quoted)
+	if n == 0 {
+		return 0, nil
+	}
+	if s.stats != nil {
+		s.stats.Substitutions = true
+	}
+	if s.unterminated[s.offset(input)] {
+		s.collect(s.errorAt(UnterminatedSubstitution, UnterminatedSubstitutionError, s.offset(input)))
+		return 0, nil
+	}
+
+	// each frame is the character closing a construct, whether that
+	// construct is a substitution, and where it starts
+	type frame struct {
+		closer byte
+		sub    bool
+		at     int
+	}
+	var stack []frame
+	depth := 0
+	push := func(closer byte, sub bool, i int) error {
+		stack = append(stack, frame{closer, sub, i})
+		if !sub {
+			return nil
+		}
+		depth++
+		if max := s.opts.MaxSubstitutionDepth; max > 0 && depth > max {
+			return s.errorAt(TooDeep, SubstitutionTooDeepError, s.offset(input)+i)
+		}
+		return nil
+	}
+	closer := func(opener string) byte {
+		if opener == "`" {
+			return '`'
+		}
+		return ')'
+	}
+
+	err := push(closer(input[:n]), true, 0)
+	for i := n; err == nil && i < len(input); {
+		top, c := stack[len(stack)-1], input[i]
+		switch {
+		case c == '\\':
+			i += 2
+			continue
+		case c == top.closer:
+			if top.sub {
+				depth--
+			}
+			stack = stack[:len(stack)-1]
+			if i++; len(stack) == 0 {
+				return i, nil
+			}
+			continue
+		case top.closer == '"':
+			if m := substitutionStart(input[i:], true); m > 0 {
+				err = push(closer(input[i:i+m]), true, i)
+				i += m
+				continue
+			}
+		case c == '\'':
+			j := strings.IndexByte(input[i+1:], '\'')
+			if j < 0 {
+				i = len(input)
+				continue
+			}
+			i += j + 2
+			continue
+		case c == '"':
+			err = push('"', false, i)
+		case c == '(':
+			err = push(')', false, i)
+		default:
+			if m := substitutionStart(input[i:], false); m > 0 {
+				err = push(closer(input[i:i+m]), true, i)
+				i += m
+				continue
+			}
+		}
+		i++
+	}
+	if err == nil {
+		err = s.errorAt(UnterminatedSubstitution, UnterminatedSubstitutionError, s.offset(input))
+		if s.opts.CollectErrors {
+			// the substitutions still open at the end of the input are
+			// unterminated as well; remember them so that reaching them
+			// later doesn't scan the rest of the input again
+			if s.unterminated == nil {
+				s.unterminated = make(map[int]bool)
+			}
+			for _, f := range stack[1:] {
+				if f.sub {
+					s.unterminated[s.offset(input)+f.at] = true
+				}
+			}
+		}
+	}
+	if s.collect(err) {
+		return 0, nil
+	}
+	return 0, err
+}
@@ -0,0 +1,89 @@
+package shellquote
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitSubstitutions(t *testing.T) {
+	opts := DefaultSplitOptions()
+	opts.Substitutions = true
+	for _, elem := range splitSubstitutionsTest {
+		output, err := SplitWithOptions(elem.input, opts)
+		if err != nil {
+			t.Errorf("Input %q, got error %v", elem.input, err)
+		} else if !reflect.DeepEqual(output, elem.output) {
+			t.Errorf("Input %q, got %q, expected %q", elem.input, output, elem.output)
+		}
+	}
+}
+
+func TestSplitSubstitutionsError(t *testing.T) {
+	opts := DefaultSplitOptions()
+	opts.Substitutions = true
+	opts.MaxSubstitutionDepth = 3
+	opts.DetailedErrors = true
+	for _, elem := range splitSubstitutionsErrorTest {
+		_, err := SplitWithOptions(elem.input, opts)
+		var serr *SplitError
+		if !errors.As(err, &serr) || serr.Kind != elem.kind || serr.Offset != elem.offset {
+			t.Errorf("Input %q, got error %v, expected %v at offset %d", elem.input, err, elem.kind, elem.offset)
+		}
+	}
+
+	if _, err := SplitWithOptions("$(a $(b `c`) $(d (e)))", opts); err != nil {
+		t.Errorf("got error %v at the maximum depth", err)
+	}
+	input := strings.Repeat("$(", 100000)
+	if _, err := SplitWithOptions(input, opts); !errors.Is(err, SubstitutionTooDeepError) {
+		t.Errorf("got error %v for deeply nested input, expected SubstitutionTooDeepError", err)
+	}
+
+	opts.CollectErrors = true
+	output, err := SplitWithOptions("a $(b c", opts)
+	if expected := []string{"a", "$(b", "c"}; !reflect.DeepEqual(output, expected) || !errors.Is(err, UnterminatedSubstitutionError) {
+		t.Errorf("got %q (%v), expected %q and UnterminatedSubstitutionError", output, err, expected)
+	}
+
+	opts.MaxSubstitutionDepth = 0
+	output, err = SplitWithOptions("$(a \"$(b\" $(c)", opts)
+	var offsets []int
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		offsets = append(offsets, e.(*SplitError).Offset)
+	}
+	if expected := []string{"$(a", "$(b", "$(c)"}; !reflect.DeepEqual(output, expected) || !reflect.DeepEqual(offsets, []int{0, 5}) {
+		t.Errorf("got %q with errors at %v, expected %q with errors at [0 5]", output, offsets, expected)
+	}
+
+	input = strings.Repeat("$( a ", 20000)
+	output, err = SplitWithOptions(input, opts)
+	if errs := err.(interface{ Unwrap() []error }).Unwrap(); len(output) != 40000 || len(errs) != 20000 {
+		t.Errorf("got %d words and %d errors, expected 40000 and 20000", len(output), len(errs))
+	}
+}
+
+var splitSubstitutionsTest = []struct {
+	input  string
+	output []string
+}{
+	{"echo $(date +%s) x", []string{"echo", "$(date +%s)", "x"}},
+	{"a=$(echo 'b )' \"c $(d e)\")f g", []string{"a=$(echo 'b )' \"c $(d e)\")f", "g"}},
+	{"echo \"x $(a b) `c d`\" `e f`", []string{"echo", "x $(a b) `c d`", "`e f`"}},
+	{"diff <(ls a) >(cat) \"<(x y)\"", []string{"diff", "<(ls a)", ">(cat)", "<(x y)"}},
+	{"echo $((1 + (2 * 3))) $(a \\) b)", []string{"echo", "$((1 + (2 * 3)))", "$(a \\) b)"}},
+	{"echo `a $(b c) \\` d`", []string{"echo", "`a $(b c) \\` d`"}},
+	{"echo '$(a b' $( a )", []string{"echo", "$(a b", "$( a )"}},
+}
+
+var splitSubstitutionsErrorTest = []struct {
+	input  string
+	kind   ErrKind
+	offset int
+}{
+	{"echo $(a b", UnterminatedSubstitution, 5},
+	{"echo \"`a b\"", UnterminatedSubstitution, 6},
+	{"x $(a \"$(b)\" 'c", UnterminatedSubstitution, 2},
+	{"$(a $(b `c $(d)`))", TooDeep, 11},
+}
@@ -0,0 +1,145 @@
+package shellquote
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	TemplateArgumentsError = errors.New("Wrong number of arguments for template")
+	InvalidTemplateError   = errors.New("Invalid command template")
+)
+
+// SplitTemplate splits a command template, like an entry of BROWSER or the
+// Exec key of a desktop entry, with the default split options and
+// substitutes args for its placeholders:
+//
+//	%s, %u, %f  the only argument, also inside a longer word
+//	%U, %F      all arguments as words of their own; must be a whole word
+//	%%          a literal %
+//
+// Other % sequences are kept as they are. Placeholders are looked for after
+// quote removal, so '%s' is replaced as well. If the template has no
+// placeholder, args are appended to it, which is how programs run the
+// command in EDITOR or GIT_EDITOR.
+//
+// Besides split errors, an error wrapping TemplateArgumentsError is
+// returned if the template has a %s, %u or %f placeholder and args doesn't
+// hold exactly one argument, and one wrapping InvalidTemplateError if a
+// %U or %F is part of a longer word or used together with a single
+// argument placeholder.
+func SplitTemplate(template string, args ...string) ([]string, error) {
+	tokens, err := SplitTokens(template, nil)
+	if err != nil {
+		return nil, err
+	}
+	found, err := checkTemplate(tokens, args)
+	if err != nil {
+		return nil, err
+	}
+	var words []string
+	for _, tok := range tokens {
+		words = append(words, substitutePlaceholders(tok.Value, args)...)
+	}
+	if !found {
+		words = append(words, args...)
+	}
+	return words, nil
+}
+
+// JoinTemplate is SplitTemplate returning a command line for sh instead of
+// the words. The words of the template without a placeholder are kept as
+// written, so that shell syntax like pipes or variables keeps working in
+// them. The words with a placeholder are quoted as a whole after the
+// substitution, like Join does, so that expansions in them aren't
+// performed.
+func JoinTemplate(template string, args ...string) (string, error) {
+	tokens, err := SplitTokens(template, nil)
+	if err != nil {
+		return "", err
+	}
+	found, err := checkTemplate(tokens, args)
+	if err != nil {
+		return "", err
+	}
+	var buf strings.Builder
+	last := 0
+	for _, tok := range tokens {
+		buf.WriteString(template[last:tok.Start])
+		if strings.IndexByte(tok.Value, '%') >= 0 {
+			buf.WriteString(Join(substitutePlaceholders(tok.Value, args)...))
+		} else {
+			buf.WriteString(template[tok.Start:tok.End])
+		}
+		last = tok.End
+	}
+	buf.WriteString(template[last:])
+	line := strings.TrimRight(buf.String(), " \t\n")
+	if !found && len(args) > 0 {
+		if line != "" {
+			line += " "
+		}
+		line += Join(args...)
+	}
+	return line, nil
+}
+
+// checkTemplate reports whether the words of a template have a placeholder,
+// and whether args fit them.
+func checkTemplate(tokens []Token, args []string) (found bool, err error) {
+	single, list := false, false
+	for _, tok := range tokens {
+		word := tok.Value
+		for i := 0; i+1 < len(word); i++ {
+			if word[i] != '%' {
+				continue
+			}
+			switch word[i+1] {
+			case 's', 'u', 'f':
+				single = true
+			case 'U', 'F':
+				if len(word) != 2 {
+					return false, fmt.Errorf("%w: %%%c in %q", InvalidTemplateError, word[i+1], word)
+				}
+				list = true
+			}
+			i++
+		}
+	}
+	switch {
+	case single && list:
+		return false, fmt.Errorf("%w: list and single argument placeholders", InvalidTemplateError)
+	case single && len(args) != 1:
+		return false, fmt.Errorf("%w: expected 1, got %d", TemplateArgumentsError, len(args))
+	}
+	return single || list, nil
+}
+
+// substitutePlaceholders returns the words word becomes after substituting
+// args for its placeholders.
+func substitutePlaceholders(word string, args []string) []string {
+	if word == "%U" || word == "%F" {
+		return args
+	}
+	if strings.IndexByte(word, '%') < 0 {
+		return []string{word}
+	}
+	var buf strings.Builder
+	for i := 0; i < len(word); i++ {
+		if word[i] == '%' && i+1 < len(word) {
+			switch word[i+1] {
+			case '%':
+				buf.WriteByte('%')
+				i++
+				continue
+			case 's', 'u', 'f':
+				buf.WriteString(args[0])
+				i++
+				continue
+			}
+		}
+		buf.WriteByte(word[i])
+	}
+	return []string{buf.String()}
+}
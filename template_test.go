@@ -0,0 +1,59 @@
+package shellquote
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSplitTemplate(t *testing.T) {
+	for _, elem := range templateTest {
+		output, err := SplitTemplate(elem.template, elem.args...)
+		if elem.err != nil {
+			if !errors.Is(err, elem.err) {
+				t.Errorf("Input %q %q, got error %v, expected %v", elem.template, elem.args, err, elem.err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(output, elem.words) {
+			t.Errorf("Input %q %q, got %q (%v), expected %q", elem.template, elem.args, output, err, elem.words)
+		}
+	}
+}
+
+func TestJoinTemplate(t *testing.T) {
+	for _, elem := range templateTest {
+		output, err := JoinTemplate(elem.template, elem.args...)
+		if elem.err != nil {
+			if !errors.Is(err, elem.err) {
+				t.Errorf("Input %q %q, got error %v, expected %v", elem.template, elem.args, err, elem.err)
+			}
+			continue
+		}
+		if err != nil || output != elem.line {
+			t.Errorf("Input %q %q, got %q (%v), expected %q", elem.template, elem.args, output, err, elem.line)
+		}
+	}
+}
+
+var templateTest = []struct {
+	template string
+	args     []string
+	words    []string
+	line     string
+	err      error
+}{
+	{"firefox %s", []string{"https://x/?a=1&b"}, []string{"firefox", "https://x/?a=1&b"}, `firefox https://x/\?a=1\&b`, nil},
+	{"lynx -dump '%s' | less", []string{"it's"}, []string{"lynx", "-dump", "it's", "|", "less"}, `lynx -dump it\'s | less`, nil},
+	{"chromium --app=%u --title='100%%'", []string{"a b"}, []string{"chromium", "--app=a b", "--title=100%"}, "chromium '--app=a b' --title=100%", nil},
+	{"code --wait  ", []string{"my file.txt", "b"}, []string{"code", "--wait", "my file.txt", "b"}, "code --wait 'my file.txt' b", nil},
+	{`vim "+set tw=$TW"`, []string{"c"}, []string{"vim", "+set tw=$TW", "c"}, `vim "+set tw=$TW" c`, nil},
+	{"gimp %U --new", []string{"a.png", "b c.png"}, []string{"gimp", "a.png", "b c.png", "--new"}, "gimp a.png 'b c.png' --new", nil},
+	{"gimp %F", nil, []string{"gimp"}, "gimp", nil},
+	{"", []string{"x"}, []string{"x"}, "x", nil},
+	{"open %s", nil, nil, "", TemplateArgumentsError},
+	{"open %s", []string{"a", "b"}, nil, "", TemplateArgumentsError},
+	{"open --files=%F", []string{"a"}, nil, "", InvalidTemplateError},
+	{"open %F %s", []string{"a"}, nil, "", InvalidTemplateError},
+	{"open 'x", []string{"a"}, nil, "", UnterminatedSingleQuoteError},
+}
@@ -0,0 +1,38 @@
+package shellquote
+
+import "strings"
+
+// QuoteTmux protects arg from tmux's parsing of its command line
+// arguments, where a ; at the end of an argument ends the tmux command and
+// is removed. Such a ; is escaped with a backslash, which tmux removes
+// again. Other arguments are returned unchanged.
+func QuoteTmux(arg string) string {
+	if strings.HasSuffix(arg, ";") {
+		return arg[:len(arg)-1] + `\;`
+	}
+	return arg
+}
+
+// TmuxSendKeys returns the arguments for tmux that type the command line
+// for args into the pane target, or the current pane if target is empty,
+// and press Enter. The command line is quoted like Join and sent with
+// send-keys -l, so that none of it is taken for a key name like Enter or
+// C-c.
+func TmuxSendKeys(target string, args []string) []string {
+	var targetArgs []string
+	if target != "" {
+		targetArgs = []string{"-t", target}
+	}
+	cmd := []string{"send-keys"}
+	cmd = append(cmd, targetArgs...)
+	cmd = append(cmd, "-l", "--", QuoteTmux(Join(args...)), ";", "send-keys")
+	cmd = append(cmd, targetArgs...)
+	return append(cmd, "Enter")
+}
+
+// TmuxNewWindow returns the arguments for tmux that open a new window
+// running args. They are passed to the shell tmux starts as a single
+// command line quoted like Join.
+func TmuxNewWindow(args []string) []string {
+	return []string{"new-window", "--", QuoteTmux(Join(args...))}
+}
@@ -0,0 +1,44 @@
+package shellquote
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQuoteTmux(t *testing.T) {
+	for _, elem := range quoteTmuxTest {
+		output := QuoteTmux(elem.input)
+		if output != elem.output {
+			t.Errorf("Input %q, got %q, expected %q", elem.input, output, elem.output)
+		}
+	}
+}
+
+func TestTmuxCommands(t *testing.T) {
+	output := TmuxSendKeys("dev:1", []string{"cd", "/my dir;x", "Enter"})
+	expected := []string{"send-keys", "-t", "dev:1", "-l", "--", `cd '/my dir;x' Enter`, ";", "send-keys", "-t", "dev:1", "Enter"}
+	if !reflect.DeepEqual(output, expected) {
+		t.Errorf("got %q, expected %q", output, expected)
+	}
+	output = TmuxSendKeys("", []string{"-v"})
+	expected = []string{"send-keys", "-l", "--", "-v", ";", "send-keys", "Enter"}
+	if !reflect.DeepEqual(output, expected) {
+		t.Errorf("got %q, expected %q", output, expected)
+	}
+	output = TmuxNewWindow([]string{"vim", "a b.txt", "x;"})
+	expected = []string{"new-window", "--", `vim 'a b.txt' x\\;`}
+	if !reflect.DeepEqual(output, expected) {
+		t.Errorf("got %q, expected %q", output, expected)
+	}
+}
+
+var quoteTmuxTest = []struct {
+	input  string
+	output string
+}{
+	{"plain", "plain"},
+	{"a; b", "a; b"},
+	{"ls;", `ls\;`},
+	{`ls\;`, `ls\\;`},
+	{";", `\;`},
+}
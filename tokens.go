@@ -0,0 +1,327 @@
+package shellquote
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+var (
+	InvalidEditError  = errors.New("Edit out of range")
+	EmptyCommandError = errors.New("Empty command")
+)
+
+// Token is a word returned by SplitTokens together with the part of the
+// input it was parsed from.
+type Token struct {
+	Value string
+	// Start and End delimit the text the word was parsed from, including
+	// any quotes and escape characters.
+	Start, End int
+	// Segments map the bytes of Value back to the input. They are ordered
+	// and cover all of Value; quote and escape characters that were removed
+	// fall between the input ranges of consecutive segments.
+	Segments []Segment
+	// Kind is a rough classification of the word.
+	Kind WordKind
+}
+
+// WordKind classifies a word by its form. It is a heuristic that doesn't
+// know which options a program accepts or which of them take an argument.
+type WordKind int
+
+const (
+	// PositionalWord is any word not covered by the other kinds.
+	PositionalWord WordKind = iota
+	// LongOptionWord starts with -- followed by something, as in
+	// --verbose or --name=value.
+	LongOptionWord
+	// ShortOptionWord starts with a single -, as in -x or -abc.
+	ShortOptionWord
+	// EndOfOptionsWord is an unquoted --.
+	EndOfOptionsWord
+	// AssignmentWord has the form NAME=value with NAME unquoted.
+	AssignmentWord
+	// OperatorWord is an unquoted control or redirection operator standing
+	// alone, like ;, &&, | or 2>. Operators attached to other text, as in
+	// a|b, are part of the word.
+	OperatorWord
+)
+
+var wordKindNames = [...]string{
+	PositionalWord:   "PositionalWord",
+	LongOptionWord:   "LongOptionWord",
+	ShortOptionWord:  "ShortOptionWord",
+	EndOfOptionsWord: "EndOfOptionsWord",
+	AssignmentWord:   "AssignmentWord",
+	OperatorWord:     "OperatorWord",
+}
+
+func (k WordKind) String() string {
+	if k >= 0 && int(k) < len(wordKindNames) {
+		return wordKindNames[k]
+	}
+	return fmt.Sprintf("WordKind(%d)", int(k))
+}
+
+var (
+	controlOperators     = map[string]bool{";": true, "&": true, "&&": true, "|": true, "||": true, "|&": true, ";;": true, "(": true, ")": true, "&>": true, "&>>": true}
+	redirectionOperators = map[string]bool{"<": true, ">": true, ">>": true, "<<": true, "<<-": true, "<<<": true, "<>": true, ">|": true}
+)
+
+// classifyWord returns the kind of the word with the given value, which
+// was parsed from raw.
+func classifyWord(value, raw string) WordKind {
+	if value == raw {
+		if value == "--" {
+			return EndOfOptionsWord
+		} else if isOperator(value) {
+			return OperatorWord
+		}
+	}
+	if n := assignmentNameLen(value); n > 0 && strings.HasPrefix(raw, value[:n+1]) {
+		return AssignmentWord
+	}
+	switch {
+	case len(value) > 2 && strings.HasPrefix(value, "--"):
+		return LongOptionWord
+	case len(value) > 1 && value[0] == '-' && value != "--":
+		return ShortOptionWord
+	}
+	return PositionalWord
+}
+
+// isOperator reports whether word is a control or redirection operator.
+// Redirections may be preceded by a file descriptor number, and >& and <&
+// followed by one or -.
+func isOperator(word string) bool {
+	if controlOperators[word] {
+		return true
+	}
+	word = strings.TrimLeft(word, "0123456789")
+	if redirectionOperators[word] {
+		return true
+	}
+	if strings.HasPrefix(word, ">&") || strings.HasPrefix(word, "<&") {
+		fd := word[2:]
+		return fd == "-" || fd != "" && strings.Trim(fd, "0123456789") == ""
+	}
+	return false
+}
+
+// Segment records that Value[Start:End] of a Token was produced from
+// input[InputStart:InputEnd].
+type Segment struct {
+	Start, End           int
+	InputStart, InputEnd int
+}
+
+// SplitTokens splits input like SplitWithOptions, but returns a Token for
+// each word that maps it back to the input.
+func SplitTokens(input string, opts *SplitOptions) ([]Token, error) {
+	return newSplitter(input, opts, true).split(input)
+}
+
+// InputOffset returns the offset in the input of the byte at offset i of
+// the token's Value. An offset of len(Value) maps to the end of the last
+// segment.
+func (t Token) InputOffset(i int) int {
+	seg, ok := t.segmentAt(i)
+	if !ok {
+		if len(t.Segments) > 0 {
+			return t.Segments[len(t.Segments)-1].InputEnd
+		}
+		return t.Start
+	}
+	if seg.End-seg.Start == seg.InputEnd-seg.InputStart {
+		return seg.InputStart + i - seg.Start
+	}
+	return seg.InputStart
+}
+
+// InputRange returns the range of the input that Value[start:end] was
+// produced from. The range includes any quote or escape characters that
+// were removed in between.
+func (t Token) InputRange(start, end int) (int, int) {
+	from := t.InputOffset(start)
+	if end <= start {
+		return from, from
+	}
+	seg, ok := t.segmentAt(end - 1)
+	if !ok {
+		return from, t.InputOffset(end)
+	}
+	if seg.End-seg.Start == seg.InputEnd-seg.InputStart {
+		return from, seg.InputStart + end - seg.Start
+	}
+	return from, seg.InputEnd
+}
+
+// segmentAt returns the segment containing offset i of Value.
+func (t Token) segmentAt(i int) (Segment, bool) {
+	for _, seg := range t.Segments {
+		if i >= seg.Start && i < seg.End {
+			return seg, true
+		}
+	}
+	return Segment{}, false
+}
+
+// Unquoted reports whether the token was taken from the input as is,
+// without any quotes or escape characters.
+func (t Token) Unquoted() bool {
+	return len(t.Value) > 0 && t.End-t.Start == len(t.Value) && len(t.Segments) <= 1
+}
+
+// EndOfOptions returns the index of the first token that is an unquoted
+// "--" word, which ends the options of most commands, or -1 if there is
+// none. A -- written with quotes or escapes, as in "--", is not recognized,
+// since such a word usually comes from a quoted value rather than from the
+// author of the command.
+func EndOfOptions(tokens []Token) int {
+	for i, tok := range tokens {
+		if tok.Value == "--" && tok.Unquoted() {
+			return i
+		}
+	}
+	return -1
+}
+
+// PartitionArgs splits tokens at the word found by EndOfOptions into the
+// tokens before it, which may hold options, and the operands after it. The
+// -- itself is in neither. Without such a word, all tokens are returned as
+// options.
+func PartitionArgs(tokens []Token) (options, operands []Token) {
+	i := EndOfOptions(tokens)
+	if i < 0 {
+		return tokens, nil
+	}
+	return tokens[:i], tokens[i+1:]
+}
+
+// ReplaceProgram splits input according to opts and replaces the word
+// naming the program to run with program, quoted like Quote does. Variable
+// assignments preceding it, as in FOO=bar cmd, are skipped. The rest of
+// input, including the quoting and spacing of all other words, is kept as
+// it was. If input has no program word, EmptyCommandError is returned.
+func ReplaceProgram(input, program string, opts *SplitOptions) (string, error) {
+	tokens, err := SplitTokens(input, opts)
+	if err != nil {
+		return input, err
+	}
+	for _, tok := range tokens {
+		if n := assignmentNameLen(tok.Value); n > 0 && tok.InputOffset(n) == tok.Start+n {
+			continue
+		}
+		return input[:tok.Start] + Quote(program) + input[tok.End:], nil
+	}
+	return input, EmptyCommandError
+}
+
+// Edit describes a change to a string: Removed bytes at Offset are replaced
+// by Inserted.
+type Edit struct {
+	Offset   int
+	Removed  int
+	Inserted string
+}
+
+// Retokenize applies edit to input and returns the edited string along
+// with its tokens. tokens must be the result of a successful
+// SplitTokens(input, opts). Only the words around the edit are split
+// again; as soon as the new tokens line up with the old ones again, the
+// remaining old tokens are reused with their offsets adjusted.
+//
+// Only options that affect single words are handled incrementally; any
+// other option, like a non-negative Limit, CollectErrors or SSHTokens,
+// makes Retokenize split the whole edited string. If the edit does not fit
+// the input, InvalidEditError is returned.
+func Retokenize(input string, tokens []Token, edit Edit, opts *SplitOptions) (string, []Token, error) {
+	if edit.Offset < 0 || edit.Removed < 0 || edit.Offset+edit.Removed > len(input) {
+		return input, tokens, InvalidEditError
+	}
+	edited := input[:edit.Offset] + edit.Inserted + input[edit.Offset+edit.Removed:]
+
+	s := newSplitter(edited, opts, true)
+	if o := s.opts; !o.wordLocal() || o.MaxLength > 0 && len(edited) > o.MaxLength {
+		result, err := s.split(edited)
+		return edited, result, err
+	}
+
+	// Split again from the end of the last token before the edit, which is
+	// a point where the splitter is known to be between words.
+	k := sort.Search(len(tokens), func(i int) bool { return tokens[i].End >= edit.Offset })
+	restart := 0
+	if k > 0 {
+		restart = tokens[k-1].End
+	}
+	result := append(make([]Token, 0, len(tokens)+1), tokens[:k]...)
+
+	delta := len(edit.Inserted) - edit.Removed
+	oldEnd, newEnd := edit.Offset+edit.Removed, edit.Offset+len(edit.Inserted)
+	rest := edited[restart:]
+	for {
+		if rest = s.skipSeparators(rest); len(rest) == 0 {
+			break
+		}
+		var tok Token
+		var err error
+		tok, rest, err = s.splitWord(rest)
+		if err != nil {
+			return edited, result, err
+		}
+		result = append(result, tok)
+
+		// Once a new token ends past the edit where an old one ended as
+		// well, the rest of the input splits the same as before.
+		if old := tok.End - delta; tok.End >= newEnd && old >= oldEnd {
+			for k < len(tokens) && tokens[k].End < old {
+				k++
+			}
+			if k < len(tokens) && tokens[k].End == old {
+				for _, t := range tokens[k+1:] {
+					result = append(result, t.shifted(delta))
+				}
+				break
+			}
+		}
+	}
+	return edited, result, nil
+}
+
+// wordLocal reports whether opts only sets options that affect how single
+// words are split, which Retokenize can apply to the words around an edit
+// alone. MaxLength is left to the caller.
+func (opts *SplitOptions) wordLocal() bool {
+	if opts.Limit >= 0 {
+		return false
+	}
+	o := *opts
+	o.SplitChars, o.SplitFunc, o.Limit = "", nil, 0
+	o.SingleChar, o.DoubleChar, o.EscapeChar, o.CommentChar = 0, 0, 0, 0
+	o.DoubleEscapeChars, o.UnquotedEscapeChars = "", ""
+	o.RejectExpansions, o.LiteralTrailingEscape, o.DetailedErrors = false, false, false
+	o.LocaleQuotes, o.AnsiCQuotes = false, false
+	o.Substitutions, o.MaxSubstitutionDepth = false, 0
+	o.MaxLength = 0
+	return reflect.ValueOf(o).IsZero()
+}
+
+// shifted returns a copy of the token moved by delta bytes in the input.
+func (t Token) shifted(delta int) Token {
+	t.Start += delta
+	t.End += delta
+	if t.Segments == nil {
+		return t
+	}
+	segments := make([]Segment, len(t.Segments))
+	for i, seg := range t.Segments {
+		seg.InputStart += delta
+		seg.InputEnd += delta
+		segments[i] = seg
+	}
+	t.Segments = segments
+	return t
+}
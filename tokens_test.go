@@ -0,0 +1,318 @@
+package shellquote
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitTokens(t *testing.T) {
+	input := `cp "my file".txt 'a b'\ c  -- rest`
+	tokens, err := SplitTokens(input, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Token{
+		{Value: "cp", Start: 0, End: 2, Segments: []Segment{{0, 2, 0, 2}}},
+		{Value: "my file.txt", Start: 3, End: 16, Segments: []Segment{{0, 7, 4, 11}, {7, 11, 12, 16}}},
+		{Value: "a b c", Start: 17, End: 25, Segments: []Segment{{0, 3, 18, 21}, {3, 4, 23, 24}, {4, 5, 24, 25}}},
+		{Value: "--", Start: 27, End: 29, Segments: []Segment{{0, 2, 27, 29}}, Kind: EndOfOptionsWord},
+		{Value: "rest", Start: 30, End: 34, Segments: []Segment{{0, 4, 30, 34}}},
+	}
+	if !reflect.DeepEqual(tokens, expected) {
+		t.Errorf("got %+v, expected %+v", tokens, expected)
+	}
+	for _, tok := range tokens {
+		for i := 0; i < len(tok.Value); i++ {
+			if j := tok.InputOffset(i); input[j] != tok.Value[i] {
+				t.Errorf("Token %q, byte %d maps to %q at %d", tok.Value, i, input[j], j)
+			}
+		}
+	}
+}
+
+func TestTokenInputRange(t *testing.T) {
+	input := `echo "a b"'c'd`
+	tokens, _ := SplitTokens(input, nil)
+	tok := tokens[1]
+	for _, elem := range []struct {
+		start, end     int
+		inStart, inEnd int
+	}{
+		{0, 1, 6, 7},
+		{0, 3, 6, 9},
+		{2, 4, 8, 12},
+		{4, 5, 13, 14},
+		{5, 5, 14, 14},
+	} {
+		start, end := tok.InputRange(elem.start, elem.end)
+		if start != elem.inStart || end != elem.inEnd {
+			t.Errorf("Range %d-%d, got %d-%d, expected %d-%d", elem.start, elem.end, start, end, elem.inStart, elem.inEnd)
+		}
+	}
+}
+
+func TestSplitTokensDoubleEscapes(t *testing.T) {
+	input := `"a\"b\\c\d"`
+	tokens, _ := SplitTokens(input, nil)
+	if len(tokens) != 1 || tokens[0].Value != `a"b\c\d` {
+		t.Fatalf("got %+v", tokens)
+	}
+	tok := tokens[0]
+	for i := 0; i < len(tok.Value); i++ {
+		if j := tok.InputOffset(i); input[j] != tok.Value[i] {
+			t.Errorf("byte %d maps to %q at %d", i, input[j], j)
+		}
+	}
+}
+
+func TestSplitTokensAnsiCQuotes(t *testing.T) {
+	opts := DefaultSplitOptions()
+	opts.AnsiCQuotes = true
+	tokens, _ := SplitTokens(`x$'a\tb\101'`, opts)
+	expected := []Token{{Value: "xa\tbA", Start: 0, End: 12, Segments: []Segment{
+		{0, 1, 0, 1}, {1, 2, 3, 4}, {2, 3, 4, 6}, {3, 4, 6, 7}, {4, 5, 7, 11},
+	}}}
+	if !reflect.DeepEqual(tokens, expected) {
+		t.Errorf("got %+v, expected %+v", tokens, expected)
+	}
+	if start, end := tokens[0].InputRange(2, 3); start != 4 || end != 6 {
+		t.Errorf("got range %d-%d for the tab, expected 4-6", start, end)
+	}
+}
+
+func TestPartitionArgs(t *testing.T) {
+	for _, elem := range partitionArgsTest {
+		tokens, _ := SplitTokens(elem.input, nil)
+		options, operands := PartitionArgs(tokens)
+		if i := EndOfOptions(tokens); i != elem.index {
+			t.Errorf("Input %q, got index %d, expected %d", elem.input, i, elem.index)
+		}
+		if got := tokenValues(options); !reflect.DeepEqual(got, elem.options) {
+			t.Errorf("Input %q, got options %q, expected %q", elem.input, got, elem.options)
+		}
+		if got := tokenValues(operands); !reflect.DeepEqual(got, elem.operands) {
+			t.Errorf("Input %q, got operands %q, expected %q", elem.input, got, elem.operands)
+		}
+	}
+}
+
+func TestWordKind(t *testing.T) {
+	for _, elem := range wordKindTest {
+		tokens, err := SplitTokens(elem.input, nil)
+		if err != nil {
+			t.Errorf("Input %q, got error %v", elem.input, err)
+			continue
+		}
+		var kinds []WordKind
+		for _, tok := range tokens {
+			kinds = append(kinds, tok.Kind)
+		}
+		if !reflect.DeepEqual(kinds, elem.kinds) {
+			t.Errorf("Input %q, got %v, expected %v", elem.input, kinds, elem.kinds)
+		}
+	}
+}
+
+func tokenValues(tokens []Token) []string {
+	var values []string
+	for _, tok := range tokens {
+		values = append(values, tok.Value)
+	}
+	return values
+}
+
+func TestReplaceProgram(t *testing.T) {
+	for _, elem := range replaceProgramTest {
+		output, err := ReplaceProgram(elem.input, elem.program, nil)
+		if err != nil {
+			t.Errorf("Input %q, got error %v", elem.input, err)
+		} else if output != elem.output {
+			t.Errorf("Input %q, got %q, expected %q", elem.input, output, elem.output)
+		}
+	}
+	if _, err := ReplaceProgram("  A=1 ", "x", nil); !errors.Is(err, EmptyCommandError) {
+		t.Errorf("got error %v, expected EmptyCommandError", err)
+	}
+}
+
+func TestSplitTokensLimit(t *testing.T) {
+	opts := DefaultSplitOptions()
+	opts.Limit = 2
+	tokens, _ := SplitTokens(" a  b 'c'  ", opts)
+	expected := []Token{
+		{Value: "a", Start: 1, End: 2, Segments: []Segment{{0, 1, 1, 2}}},
+		{Value: "b 'c'", Start: 4, End: 9, Segments: []Segment{{0, 5, 4, 9}}},
+	}
+	if !reflect.DeepEqual(tokens, expected) {
+		t.Errorf("got %+v, expected %+v", tokens, expected)
+	}
+}
+
+func TestRetokenize(t *testing.T) {
+	for _, elem := range retokenizeTest {
+		tokens, err := SplitTokens(elem.input, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		edited, got, err := Retokenize(elem.input, tokens, elem.edit, nil)
+		want, wantErr := SplitTokens(edited, nil)
+		if edited != elem.edited {
+			t.Errorf("Input %q, edit %+v, got %q, expected %q", elem.input, elem.edit, edited, elem.edited)
+		}
+		if (err == nil) != (wantErr == nil) || err == nil && !reflect.DeepEqual(got, want) {
+			t.Errorf("Input %q, edit %+v, got %+v (%v), expected %+v (%v)", elem.input, elem.edit, got, err, want, wantErr)
+		}
+	}
+}
+
+func TestRetokenizeRandom(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	const alphabet = "ab '\"\\\n"
+	randomString := func(n int) string {
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = alphabet[rnd.Intn(len(alphabet))]
+		}
+		return string(b)
+	}
+	for i := 0; i < 5000; i++ {
+		input := randomString(rnd.Intn(20))
+		tokens, err := SplitTokens(input, nil)
+		if err != nil {
+			continue
+		}
+		offset := rnd.Intn(len(input) + 1)
+		edit := Edit{Offset: offset, Removed: rnd.Intn(len(input) - offset + 1), Inserted: randomString(rnd.Intn(4))}
+		edited, got, err := Retokenize(input, tokens, edit, nil)
+		want, wantErr := SplitTokens(edited, nil)
+		if (err == nil) != (wantErr == nil) || err == nil && !reflect.DeepEqual(got, want) {
+			t.Fatalf("Input %q, edit %+v, got %+v (%v), expected %+v (%v)", input, edit, got, err, want, wantErr)
+		}
+	}
+}
+
+func TestRetokenizeLiteralUnmatchedQuotes(t *testing.T) {
+	opts := DefaultSplitOptions()
+	opts.LiteralUnmatchedQuotes = true
+	input := "a\"b c"
+	tokens, err := SplitTokens(input, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edit := Edit{Offset: 5, Inserted: "\""}
+	edited, got, err := Retokenize(input, tokens, edit, opts)
+	want, wantErr := SplitTokens(edited, opts)
+	if err != nil || wantErr != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("Input %q, edit %+v, got %+v (%v), expected %+v (%v)", input, edit, got, err, want, wantErr)
+	}
+}
+
+func TestRetokenizeOptions(t *testing.T) {
+	for name, set := range map[string]func(*SplitOptions){
+		"SplitChars":             func(o *SplitOptions) { o.SplitChars = " ," },
+		"SplitFunc":              func(o *SplitOptions) { o.SplitFunc = func(c rune) bool { return c == ',' || c == ' ' } },
+		"Limit":                  func(o *SplitOptions) { o.Limit = 2 },
+		"RejectExpansions":       func(o *SplitOptions) { o.RejectExpansions = true },
+		"LiteralUnmatchedQuotes": func(o *SplitOptions) { o.LiteralUnmatchedQuotes = true },
+		"UnquotedEscapeChars":    func(o *SplitOptions) { o.UnquotedEscapeChars = "\\ " },
+		"LiteralTrailingEscape":  func(o *SplitOptions) { o.LiteralTrailingEscape = true },
+		"CollectErrors":          func(o *SplitOptions) { o.CollectErrors = true },
+		"DetailedErrors":         func(o *SplitOptions) { o.DetailedErrors = true },
+		"RejectNUL":              func(o *SplitOptions) { o.RejectNUL = true },
+		"StripBOM":               func(o *SplitOptions) { o.StripBOM = true },
+		"RejectUTF16":            func(o *SplitOptions) { o.RejectUTF16 = true },
+		"MaxLength":              func(o *SplitOptions) { o.MaxLength = 10 },
+		"CommentChar":            func(o *SplitOptions) { o.CommentChar = '#' },
+		"LocaleQuotes":           func(o *SplitOptions) { o.LocaleQuotes = true },
+		"AnsiCQuotes":            func(o *SplitOptions) { o.AnsiCQuotes = true },
+		"SSHTokens":              func(o *SplitOptions) { o.SSHTokens = true },
+		"Substitutions":          func(o *SplitOptions) { o.Substitutions = true },
+		"TransformWord": func(o *SplitOptions) {
+			o.TransformWord = func(tok Token) (string, bool) { return tok.Value, tok.Value != "a" }
+		},
+	} {
+		opts := DefaultSplitOptions()
+		set(opts)
+		rnd := rand.New(rand.NewSource(1))
+		alphabet := []string{"a", "b", " ", ",", "'", "\"", "\\", "\n", "#", "$", "(", ")", "`", "%", "\x00", "\ufeff", "\xff\xfe"}
+		randomString := func(n int) string {
+			var buf strings.Builder
+			for i := 0; i < n; i++ {
+				buf.WriteString(alphabet[rnd.Intn(len(alphabet))])
+			}
+			return buf.String()
+		}
+		for i := 0; i < 2000; i++ {
+			input := randomString(rnd.Intn(12))
+			tokens, err := SplitTokens(input, opts)
+			if err != nil {
+				continue
+			}
+			offset := rnd.Intn(len(input) + 1)
+			edit := Edit{Offset: offset, Removed: rnd.Intn(len(input) - offset + 1), Inserted: randomString(rnd.Intn(3))}
+			edited, got, err := Retokenize(input, tokens, edit, opts)
+			want, wantErr := SplitTokens(edited, opts)
+			if (err == nil) != (wantErr == nil) || err == nil && !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: input %q, edit %+v, got %+v (%v), expected %+v (%v)", name, input, edit, got, err, want, wantErr)
+				break
+			}
+		}
+	}
+}
+
+func TestRetokenizeInvalidEdit(t *testing.T) {
+	if _, _, err := Retokenize("abc", nil, Edit{Offset: 2, Removed: 2}, nil); err != InvalidEditError {
+		t.Errorf("got error %v, expected InvalidEditError", err)
+	}
+}
+
+var partitionArgsTest = []struct {
+	input    string
+	index    int
+	options  []string
+	operands []string
+}{
+	{"rm -f -- -x y", 2, []string{"rm", "-f"}, []string{"-x", "y"}},
+	{`grep "--" -- -e`, 2, []string{"grep", "--"}, []string{"-e"}},
+	{`echo \-- '--' -""- a`, -1, []string{"echo", "--", "--", "--", "a"}, nil},
+	{"ls --", 1, []string{"ls"}, nil},
+}
+
+var wordKindTest = []struct {
+	input string
+	kinds []WordKind
+}{
+	{"ls -la --color=auto -- - x", []WordKind{PositionalWord, ShortOptionWord, LongOptionWord, EndOfOptionsWord, PositionalWord, PositionalWord}},
+	{`'-x' \--y '--' \-- ""--`, []WordKind{ShortOptionWord, LongOptionWord, PositionalWord, PositionalWord, PositionalWord}},
+	{`FOO=1 _b='a b' 'C=2' D\=3 x=`, []WordKind{AssignmentWord, AssignmentWord, PositionalWord, PositionalWord, AssignmentWord}},
+	{"a ; b && c | d || e & ( f ) ;; |&", []WordKind{0, OperatorWord, 0, OperatorWord, 0, OperatorWord, 0, OperatorWord, 0, OperatorWord, OperatorWord, 0, OperatorWord, OperatorWord, OperatorWord}},
+	{"cmd < in > out 2>> log 2>&1 <&- &> all <<< s 3<> f", []WordKind{0, OperatorWord, 0, OperatorWord, 0, OperatorWord, 0, OperatorWord, OperatorWord, OperatorWord, 0, OperatorWord, 0, OperatorWord, 0}},
+	{`a '|' \; a|b 2\; >&x 2&`, []WordKind{0, 0, 0, 0, 0, 0, 0}},
+}
+
+var replaceProgramTest = []struct {
+	input   string
+	program string
+	output  string
+}{
+	{"ls  -l 'a  b'", "/bin/ls", "/bin/ls  -l 'a  b'"},
+	{"  \"my prog\"\targ", "/opt/My Apps/prog", "  '/opt/My Apps/prog'\targ"},
+	{"A=1 'B'=2 cmd x", "wrap", "A=1 wrap cmd x"},
+}
+
+var retokenizeTest = []struct {
+	input  string
+	edit   Edit
+	edited string
+}{
+	{"echo hello world", Edit{Offset: 5, Removed: 5, Inserted: "goodbye"}, "echo goodbye world"},
+	{"echo hello world", Edit{Offset: 10, Removed: 1}, "echo helloworld"},
+	{"echo hello world", Edit{Offset: 16, Inserted: " again"}, "echo hello world again"},
+	{"echo hello world", Edit{Offset: 0, Inserted: "sudo "}, "sudo echo hello world"},
+	{"echo 'a b' c d", Edit{Offset: 5, Removed: 1}, "echo a b' c d"},
+	{"echo a b 'c d'", Edit{Offset: 7, Inserted: "'"}, "echo a 'b 'c d'"},
+	{"a \\\nb c", Edit{Offset: 4, Removed: 1, Inserted: "x"}, "a \\\nx c"},
+}
@@ -0,0 +1,212 @@
+package shellquote
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// TranscriptOptions configures ParseTranscript.
+type TranscriptOptions struct {
+	// PromptChars are the characters a prompt may end in. The prompt
+	// character must be followed by a space or the end of the line.
+	PromptChars string
+	// ContinuationPrompt starts the lines that continue a command, like
+	// bash's default PS2 of "> ". A space following it is removed too.
+	ContinuationPrompt string
+	// Split configures splitting the commands. nil means bash's quoting
+	// rules with comments and command substitutions recognized, as for
+	// ParseHistory.
+	Split *SplitOptions
+}
+
+func DefaultTranscriptOptions() *TranscriptOptions {
+	return &TranscriptOptions{PromptChars: "$#", ContinuationPrompt: ">"}
+}
+
+// TranscriptCommand is a command found in a terminal transcript by
+// ParseTranscript.
+type TranscriptCommand struct {
+	// Prompt is the prompt in front of the command, like "$" or
+	// "user@host:~/src$", without the space following it.
+	Prompt string
+	// Command is the command without its prompts. The lines of a
+	// multi-line command are separated by newlines.
+	Command string
+	// Args is Command split according to the options.
+	Args []string
+	// Err is the error splitting Command, if any.
+	Err error
+	// Output holds the lines following the command up to the next one,
+	// separated by newlines.
+	Output string
+	// Line and EndLine are the 1-based numbers of the first and last line
+	// of the command, and Column is the 1-based byte column the command
+	// starts at on its first line.
+	Line, EndLine, Column int
+}
+
+// ParseTranscript reads a pasted terminal session from r and returns the
+// commands in it. A line holds a command if it starts with a prompt: a
+// prompt character like $ on its own, or at the end of a word like
+// user@host:~/src$ or ~/src$ that looks like a location, or after a
+// bracketed part like [user@host src]$. The prompt may be indented and
+// preceded by a parenthesized virtual environment name, as in (venv) $.
+//
+// A command continues on the following lines while they start with the
+// continuation prompt, and also on lines without it while the command ends
+// inside a quoted string, in a backslash or in a | or && or || operator,
+// since copied transcripts often lack the continuation prompts. All other
+// lines are taken as output of the command before them; lines before the
+// first command are ignored, as are prompts without a command.
+//
+// Any line of output that looks like a prompt, like a # comment printed by
+// cat, is mistaken for a command. Commands that can't be split are
+// returned with Err set; the error returned by ParseTranscript is only
+// ever an error reading from r.
+func ParseTranscript(r io.Reader, opts *TranscriptOptions) ([]TranscriptCommand, error) {
+	if opts == nil {
+		opts = DefaultTranscriptOptions()
+	}
+	split := opts.Split
+	if split == nil {
+		split = bashSplitOptions()
+		split.CommentChar = '#'
+		split.Substitutions = true
+		split.CollectErrors = true
+	}
+
+	var commands []TranscriptCommand
+	inCommand, open := false, false
+	br := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		text, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return transcriptCommands(commands), err
+		} else if err == io.EOF && len(text) == 0 {
+			return transcriptCommands(commands), nil
+		}
+		text = strings.TrimSuffix(strings.TrimSuffix(text, "\n"), "\r")
+
+		rest, continued := opts.continuation(text)
+		if inCommand && (continued || open) {
+			c := &commands[len(commands)-1]
+			if !continued {
+				rest = text
+			}
+			c.Command += "\n" + rest
+			c.EndLine = line
+			c.Args, c.Err = SplitWithOptions(c.Command, split)
+			open = continues(c)
+		} else if n := opts.promptLen(text); n >= 0 {
+			c := TranscriptCommand{
+				Prompt:  strings.TrimSpace(text[:n]),
+				Command: text[n:],
+				Line:    line,
+				EndLine: line,
+				Column:  n + 1,
+			}
+			c.Args, c.Err = SplitWithOptions(c.Command, split)
+			commands = append(commands, c)
+			inCommand, open = true, continues(&c)
+		} else if len(commands) > 0 {
+			c := &commands[len(commands)-1]
+			if inCommand {
+				c.Output = text
+			} else {
+				c.Output += "\n" + text
+			}
+			inCommand, open = false, false
+		}
+		if err == io.EOF {
+			return transcriptCommands(commands), nil
+		}
+	}
+}
+
+// transcriptCommands drops the prompts without a command.
+func transcriptCommands(commands []TranscriptCommand) []TranscriptCommand {
+	kept := commands[:0]
+	for _, c := range commands {
+		if strings.TrimSpace(c.Command) != "" {
+			kept = append(kept, c)
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return kept
+}
+
+// continues reports whether the command c is incomplete and continues on
+// the next line even without a continuation prompt.
+func continues(c *TranscriptCommand) bool {
+	if incomplete(c.Err) {
+		return true
+	}
+	text := strings.TrimRight(c.Command, " \t")
+	for _, op := range []string{"|", "&&"} {
+		if strings.HasSuffix(text, op) && !strings.HasSuffix(text, `\`+op) {
+			return true
+		}
+	}
+	return false
+}
+
+// continuation returns the rest of line if it starts with the
+// continuation prompt.
+func (opts *TranscriptOptions) continuation(line string) (string, bool) {
+	prompt := opts.ContinuationPrompt
+	if prompt == "" || !strings.HasPrefix(line, prompt) {
+		return line, false
+	}
+	rest := line[len(prompt):]
+	if rest == "" {
+		return rest, true
+	} else if rest[0] == ' ' {
+		return rest[1:], true
+	}
+	return line, false
+}
+
+// promptLen returns the length of the prompt line starts with, including
+// the space following it, or -1 if it doesn't start with one.
+func (opts *TranscriptOptions) promptLen(line string) int {
+	i := len(line) - len(strings.TrimLeft(line, " \t"))
+	if strings.HasPrefix(line[i:], "(") {
+		end := strings.IndexByte(line[i:], ')')
+		if end < 0 {
+			return -1
+		}
+		i += end + 1
+		i = len(line) - len(strings.TrimLeft(line[i:], " \t"))
+	}
+
+	rest := line[i:]
+	var end int // offset of the prompt character in rest
+	if strings.HasPrefix(rest, "[") {
+		end = strings.IndexByte(rest, ']') + 1
+		if end == 0 {
+			return -1
+		}
+	} else {
+		end = strings.IndexAny(rest, " \t")
+		if end < 0 {
+			end = len(rest)
+		}
+		end--
+		if end > 0 && rest[0] != '~' && !strings.ContainsAny(rest[:end], "@:") {
+			return -1
+		}
+	}
+	if end < 0 || end >= len(rest) || strings.IndexByte(opts.PromptChars, rest[end]) < 0 {
+		return -1
+	}
+	switch n := i + end + 1; {
+	case n == len(line):
+		return n
+	case line[n] == ' ' || line[n] == '\t':
+		return n + 1
+	}
+	return -1
+}
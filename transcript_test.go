@@ -0,0 +1,85 @@
+package shellquote
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTranscript(t *testing.T) {
+	input := "Install it like this:\n" +
+		"\n" +
+		"$ git clone https://example.com/repo.git\n" +
+		"Cloning into 'repo'...\n" +
+		"user@host:~/src$ cd repo && make \\\n" +
+		">   PREFIX=\"$HOME/.local\"\n" +
+		"make: Nothing to be done.\n" +
+		"\n" +
+		"(venv) [root@box repo]# cat <<EOF | sort\r\n" +
+		"> b\r\n" +
+		"> a\r\n" +
+		"> EOF\r\n" +
+		"a\r\n" +
+		"b\r\n" +
+		"  $ echo 'multi\n" +
+		"line' |\n" +
+		"    wc -l\n" +
+		"2\n" +
+		"$\n" +
+		"~/x% not a prompt\n" +
+		"$ echo 'open\n"
+	commands, err := ParseTranscript(strings.NewReader(input), nil)
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	for i := range commands {
+		if commands[i].Err != nil && i < len(commands)-1 {
+			t.Errorf("command %d: got error %v", i, commands[i].Err)
+		}
+		commands[i].Err = nil
+	}
+	expected := []TranscriptCommand{
+		{Prompt: "$", Command: "git clone https://example.com/repo.git", Args: []string{"git", "clone", "https://example.com/repo.git"},
+			Output: "Cloning into 'repo'...", Line: 3, EndLine: 3, Column: 3},
+		{Prompt: "user@host:~/src$", Command: "cd repo && make \\\n  PREFIX=\"$HOME/.local\"", Args: []string{"cd", "repo", "&&", "make", "PREFIX=$HOME/.local"},
+			Output: "make: Nothing to be done.\n", Line: 5, EndLine: 6, Column: 18},
+		{Prompt: "(venv) [root@box repo]#", Command: "cat <<EOF | sort\nb\na\nEOF", Args: []string{"cat", "<<EOF", "|", "sort", "b", "a", "EOF"},
+			Output: "a\nb", Line: 9, EndLine: 12, Column: 25},
+		{Prompt: "$", Command: "echo 'multi\nline' |\n    wc -l", Args: []string{"echo", "multi\nline", "|", "wc", "-l"},
+			Output: "2", Line: 15, EndLine: 17, Column: 5},
+		{Prompt: "$", Command: "echo 'open", Args: []string{"echo", "'open"}, Line: 21, EndLine: 21, Column: 3},
+	}
+	if !reflect.DeepEqual(commands, expected) {
+		t.Errorf("got\n%+v\nexpected\n%+v", commands, expected)
+	}
+}
+
+func TestTranscriptPrompt(t *testing.T) {
+	opts := DefaultTranscriptOptions()
+	for _, elem := range transcriptPromptTest {
+		if n := opts.promptLen(elem.input); n != elem.n {
+			t.Errorf("Input %q, got %d, expected %d", elem.input, n, elem.n)
+		}
+	}
+}
+
+var transcriptPromptTest = []struct {
+	input string
+	n     int
+}{
+	{"$ ls", 2},
+	{"$", 1},
+	{"# apt update", 2},
+	{"  $ ls", 4},
+	{"me@host:/tmp$ ls", 14},
+	{"~$ ls", 3},
+	{"[me@host tmp]$ ls", 15},
+	{"(.venv) $ pip list", 10},
+	{"$ls", -1},
+	{"costs 5$ each", -1},
+	{"5$ each", -1},
+	{"#!/bin/sh", -1},
+	{"[me@host tmp] ls", -1},
+	{"> continued", -1},
+	{"", -1},
+}
@@ -0,0 +1,179 @@
+package shellquote
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+var (
+	InvalidValueError    = errors.New("Invalid value")
+	TooManyOperandsError = errors.New("Too many operands")
+)
+
+// Unmarshal parses the options and operands in args, which must not include
+// the program name, and stores them in the struct v points to. The fields
+// are described by cmd tags as for Marshal; the eq and omitempty flags have
+// no effect.
+//
+// The options are parsed by ParseArgs with Permute set. Options of bool
+// fields take no argument and set the field to true. All others take one,
+// which is converted to the field's type; slice fields, including []bool,
+// collect the values of repeated options. Operands are assigned to the
+// positional fields in order, with a slice field taking all remaining ones.
+//
+// Fields that don't appear in args are left unchanged. Besides the errors
+// of ParseArgs, an *OptionError wrapping InvalidValueError is returned for
+// an option argument that can't be converted, and an error wrapping
+// InvalidValueError or TooManyOperandsError for an operand that can't be
+// stored.
+func Unmarshal(args []string, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("%w: expected a non-nil pointer to a struct, got %T", UnsupportedTypeError, v)
+	}
+	rv, err := structValue(rv)
+	if err != nil {
+		return err
+	}
+	fields, err := cmdFields(rv.Type(), nil)
+	if err != nil {
+		return err
+	}
+
+	spec := &OptSpec{Permute: true}
+	options := make(map[string]cmdField)
+	var positional []cmdField
+	for _, f := range fields {
+		if f.positional {
+			positional = append(positional, f)
+			continue
+		}
+		options[f.option] = f
+		takesArgument := !isBoolField(rv.FieldByIndex(f.index))
+		if f.option[1] == '-' {
+			name := f.option[2:]
+			if takesArgument {
+				name += "="
+			}
+			spec.Long = append(spec.Long, name)
+		} else {
+			spec.Short += f.option[1:]
+			if takesArgument {
+				spec.Short += ":"
+			}
+		}
+	}
+
+	opts, operands, err := ParseArgs(args, spec)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		option := "-" + opt.Name
+		if opt.Long {
+			option = "-" + option
+		}
+		f := options[option]
+		if err := storeText(rv.FieldByIndex(f.index), opt.Value, f); err != nil {
+			return &OptionError{Option: option, Index: opt.Index, Err: err}
+		}
+	}
+	for _, f := range positional {
+		if len(operands) == 0 {
+			break
+		}
+		fv := rv.FieldByIndex(f.index)
+		n := 1
+		if fv.Kind() == reflect.Slice && !isTextUnmarshaler(fv) {
+			n = len(operands)
+		}
+		for _, operand := range operands[:n] {
+			if err := storeText(fv, operand, f); err != nil {
+				return fmt.Errorf("%w: operand %q for field %s", err, operand, f.name)
+			}
+		}
+		operands = operands[n:]
+	}
+	if len(operands) > 0 {
+		return fmt.Errorf("%w: %q", TooManyOperandsError, operands)
+	}
+	return nil
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// isTextUnmarshaler reports whether a pointer to v implements
+// encoding.TextUnmarshaler.
+func isTextUnmarshaler(v reflect.Value) bool {
+	return reflect.PointerTo(v.Type()).Implements(textUnmarshalerType)
+}
+
+// storeText stores text in the field v. Pointers are allocated as needed,
+// slices get text appended, and bool options are set to true. Bool operands
+// and slice elements are parsed with strconv.ParseBool. The returned
+// error wraps InvalidValueError or UnsupportedTypeError.
+func storeText(v reflect.Value, text string, f cmdField) error {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if isTextUnmarshaler(v) {
+		if err := v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text)); err != nil {
+			return fmt.Errorf("%w: %v", InvalidValueError, err)
+		}
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Slice:
+		elem := reflect.New(v.Type().Elem()).Elem()
+		// the elements of a slice option are given as arguments, so bool
+		// elements are parsed like those of operands
+		f.positional = true
+		if err := storeText(elem, text, f); err != nil {
+			return err
+		}
+		v.Set(reflect.Append(v, elem))
+		return nil
+	case reflect.Bool:
+		if !f.positional {
+			v.SetBool(true)
+			return nil
+		}
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return InvalidValueError
+		}
+		v.SetBool(b)
+		return nil
+	case reflect.String:
+		v.SetString(text)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(text, 10, v.Type().Bits())
+		if err != nil {
+			return InvalidValueError
+		}
+		v.SetInt(n)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(text, 10, v.Type().Bits())
+		if err != nil {
+			return InvalidValueError
+		}
+		v.SetUint(n)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(text, v.Type().Bits())
+		if err != nil {
+			return InvalidValueError
+		}
+		v.SetFloat(n)
+		return nil
+	}
+	return fmt.Errorf("%w: field %s has type %s", UnsupportedTypeError, f.name, v.Type())
+}
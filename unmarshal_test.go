@@ -0,0 +1,91 @@
+package shellquote
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestUnmarshal(t *testing.T) {
+	var args marshalArgs
+	input := []string{"src", "-vl", "7", "--out=a b", "--tag", "x", "--ratio", "16", "--tag=y", "--addr", "::1", "--limit", "5", "--force", "--", "-dst", "z"}
+	if err := Unmarshal(input, &args); err != nil {
+		t.Fatal(err)
+	}
+	limit, force := uint(5), true
+	expected := marshalArgs{
+		marshalBase: marshalBase{Verbose: true},
+		Output:      "a b",
+		Level:       7,
+		Ratio:       16,
+		Tags:        []string{"x", "y"},
+		Addr:        net.ParseIP("::1"),
+		Limit:       &limit,
+		Force:       &force,
+		Source:      "src",
+		Targets:     []string{"-dst", "z"},
+	}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("got %+v, expected %+v", args, expected)
+	}
+
+	marshaled, err := Marshal(&expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var again marshalArgs
+	if err := Unmarshal(marshaled, &again); err != nil || !reflect.DeepEqual(again, expected) {
+		t.Errorf("Marshaled as %q, got %+v (%v) back", marshaled, again, err)
+	}
+}
+
+func TestUnmarshalBoolSlice(t *testing.T) {
+	type boolArgs struct {
+		Flags []bool `cmd:"--flag"`
+	}
+	expected := boolArgs{Flags: []bool{false, true, false}}
+	marshaled, err := Marshal(expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var args boolArgs
+	if err := Unmarshal(marshaled, &args); err != nil || !reflect.DeepEqual(args, expected) {
+		t.Errorf("Marshaled as %q, got %+v (%v) back", marshaled, args, err)
+	}
+	if err := Unmarshal([]string{"--flag", "maybe"}, &args); !errors.Is(err, InvalidValueError) {
+		t.Errorf("got error %v, expected InvalidValueError", err)
+	}
+}
+
+func TestUnmarshalError(t *testing.T) {
+	for _, elem := range []struct {
+		input []string
+		err   error
+	}{
+		{[]string{"-l", "x"}, InvalidValueError},
+		{[]string{"--addr", "nowhere"}, InvalidValueError},
+		{[]string{"--limit=-1"}, InvalidValueError},
+		{[]string{"--unknown"}, UnknownOptionError},
+		{[]string{"-v=1"}, UnknownOptionError},
+		{[]string{"--output"}, MissingArgumentError},
+	} {
+		var args marshalArgs
+		if err := Unmarshal(elem.input, &args); !errors.Is(err, elem.err) {
+			t.Errorf("Input %q, got error %v, expected %v", elem.input, err, elem.err)
+		}
+	}
+
+	var flag struct {
+		On bool `cmd:",positional"`
+	}
+	if err := Unmarshal([]string{"maybe"}, &flag); !errors.Is(err, InvalidValueError) {
+		t.Errorf("got error %v, expected InvalidValueError", err)
+	}
+	if err := Unmarshal([]string{"true", "extra"}, &flag); !errors.Is(err, TooManyOperandsError) || !flag.On {
+		t.Errorf("got error %v, expected TooManyOperandsError", err)
+	}
+	if err := Unmarshal(nil, flag); !errors.Is(err, UnsupportedTypeError) {
+		t.Errorf("got error %v for a non-pointer, expected UnsupportedTypeError", err)
+	}
+}
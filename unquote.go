@@ -3,16 +3,104 @@ package shellquote
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"strings"
 	"unicode/utf8"
 )
 
 var (
-	UnterminatedSingleQuoteError = errors.New("Unterminated single-quoted string")
-	UnterminatedDoubleQuoteError = errors.New("Unterminated double-quoted string")
-	UnterminatedEscapeError      = errors.New("Unterminated backslash-escape")
+	UnterminatedSingleQuoteError  = errors.New("Unterminated single-quoted string")
+	UnterminatedDoubleQuoteError  = errors.New("Unterminated double-quoted string")
+	UnterminatedEscapeError       = errors.New("Unterminated backslash-escape")
+	InvalidOptionsError           = errors.New("Invalid split options")
+	NULByteError                  = errors.New("NUL byte in input")
+	InputTooLongError             = errors.New("Input too long")
+	InvalidSSHTokenError          = errors.New("Invalid OpenSSH token")
+	UnterminatedSubstitutionError = errors.New("Unterminated substitution")
+	SubstitutionTooDeepError      = errors.New("Substitutions nested too deeply")
+	UTF16InputError               = errors.New("Input is UTF-16 encoded")
+	UnsetParameterError           = errors.New("Parameter not set")
 )
 
+// ErrKind classifies the problems reported by SplitError.
+type ErrKind int
+
+const (
+	UnknownErrKind ErrKind = iota
+	UnterminatedSingle
+	UnterminatedDouble
+	UnterminatedEscape
+	UnsupportedExpansion
+	NULByte
+	TooLong
+	UnterminatedParameter
+	InvalidSSHToken
+	UnterminatedSubstitution
+	TooDeep
+	UTF16Input
+	UnsetParameter
+)
+
+var errKindNames = [...]string{
+	UnknownErrKind:           "UnknownErrKind",
+	UnterminatedSingle:       "UnterminatedSingle",
+	UnterminatedDouble:       "UnterminatedDouble",
+	UnterminatedEscape:       "UnterminatedEscape",
+	UnsupportedExpansion:     "UnsupportedExpansion",
+	NULByte:                  "NULByte",
+	TooLong:                  "TooLong",
+	UnterminatedParameter:    "UnterminatedParameter",
+	InvalidSSHToken:          "InvalidSSHToken",
+	UnterminatedSubstitution: "UnterminatedSubstitution",
+	TooDeep:                  "TooDeep",
+	UTF16Input:               "UTF16Input",
+	UnsetParameter:           "UnsetParameter",
+}
+
+func (k ErrKind) String() string {
+	if k >= 0 && int(k) < len(errKindNames) {
+		return errKindNames[k]
+	}
+	return fmt.Sprintf("ErrKind(%d)", int(k))
+}
+
+// ExpansionError is returned, or wrapped by the SplitError returned, when
+// RejectExpansions is set and the input contains a construct that a shell
+// would expand but the splitter leaves alone.
+type ExpansionError struct {
+	// Construct is the leading part of the expansion, like "$HOME", "${",
+	// "$(" or "`".
+	Construct string
+	// Offset is the byte offset of the construct in the input.
+	Offset int
+}
+
+func (e *ExpansionError) Error() string {
+	return "Unsupported expansion " + e.Construct
+}
+
+// SplitError is the error returned by SplitWithOptions if DetailedErrors or
+// CollectErrors is set. It records what kind of problem was found and where
+// in the input. Err is one of the
+// package's error values, or an *ExpansionError, and can be tested for with
+// errors.Is and errors.As.
+type SplitError struct {
+	Kind ErrKind
+	Err  error
+	// Offset is the byte offset of the problem in the input. For
+	// unterminated quotes and escapes it is the offset of the opening
+	// character.
+	Offset int
+}
+
+func (e *SplitError) Error() string {
+	return fmt.Sprintf("%s at offset %d", e.Err, e.Offset)
+}
+
+func (e *SplitError) Unwrap() error {
+	return e.Err
+}
+
 const (
 	DefaultSplitChars        = " \n\t"
 	DefaultSingleChar        = '\''
@@ -21,6 +109,14 @@ const (
 	DefaultDoubleEscapeChars = "$`\"\n\\"
 )
 
+// SplitOptions configures SplitWithOptions. A zero rune disables the
+// corresponding quote or escape character, and a negative Limit means no
+// limit.
+//
+// Options may be shared between goroutines, but must not be modified while
+// any of them is splitting with it. SplitWithOptions copies the options when
+// it is called, so changes made afterwards never affect a split in progress;
+// use Clone to derive a modified variant of shared options.
 type SplitOptions struct {
 	SplitChars        string
 	SingleChar        rune
@@ -28,6 +124,116 @@ type SplitOptions struct {
 	EscapeChar        rune
 	DoubleEscapeChars string
 	Limit             int
+
+	// SplitFunc, if set, is used instead of SplitChars to decide whether a
+	// character separates words, so that separators like all Unicode spaces
+	// and a comma can be given without listing them, as in
+	// func(r rune) bool { return unicode.IsSpace(r) || r == ',' }.
+	SplitFunc func(rune) bool
+
+	// RejectExpansions makes splitting fail with an *ExpansionError when
+	// the input contains a parameter expansion, command or arithmetic
+	// substitution, process substitution or a leading tilde outside of
+	// single-quotes. Pathname and brace expansion characters are still
+	// passed through literally. The unsplit remainder returned once Limit
+	// is reached is checked too, following its quotes.
+	RejectExpansions bool
+
+	// LiteralUnmatchedQuotes keeps a quote character that has no closing
+	// counterpart as a literal character instead of returning
+	// UnterminatedSingleQuoteError or UnterminatedDoubleQuoteError. The
+	// text following it is split as if the quote wasn't there.
+	LiteralUnmatchedQuotes bool
+
+	// UnquotedEscapeChars, if set, limits the characters EscapeChar escapes
+	// outside of quotes, as DoubleEscapeChars does inside double-quotes. An
+	// escape character followed by any other character is kept as a literal
+	// character. Limited to the quote and escape characters and the split
+	// characters, backslashes in Windows paths like C:\Users are kept. An
+	// escaped newline is only removed if the newline is listed.
+	UnquotedEscapeChars string
+
+	// LiteralTrailingEscape keeps an escape character at the very end of
+	// the input as a literal character instead of returning
+	// UnterminatedEscapeError.
+	LiteralTrailingEscape bool
+
+	// CollectErrors makes splitting continue past unterminated quotes,
+	// trailing escapes and rejected expansions, treating them as literal
+	// text. All words are returned together with an error joining a
+	// *SplitError for every problem found.
+	CollectErrors bool
+
+	// DetailedErrors makes splitting fail with a *SplitError recording the
+	// kind and offset of the problem, which wraps the error that is
+	// returned otherwise. Errors collected with CollectErrors are always
+	// detailed.
+	DetailedErrors bool
+
+	// RejectNUL makes splitting fail with NULByteError if the input
+	// contains a NUL byte, which can never be part of a process argument.
+	RejectNUL bool
+
+	// StripBOM drops a UTF-8 byte order mark at the start of the input, as
+	// written by some Windows editors, instead of making it part of the
+	// first word. Offsets still refer to the input including the mark.
+	StripBOM bool
+
+	// RejectUTF16 makes splitting fail with UTF16InputError if the input
+	// starts with a UTF-16 byte order mark, which suggests it was read from
+	// a file that needs to be decoded first.
+	RejectUTF16 bool
+
+	// MaxLength, if positive, is the maximum length of the input in bytes.
+	// Longer input fails with InputTooLongError before any splitting is
+	// done, even if CollectErrors is set.
+	MaxLength int
+
+	// CommentChar, if set, starts a comment when it appears at the start of
+	// a word. The comment extends up to the next newline.
+	CommentChar rune
+
+	// LocaleQuotes makes $"..." behave like a double-quoted string, as in
+	// bash, instead of a literal $ followed by one. No translation takes
+	// place, so RejectExpansions rejects such strings.
+	LocaleQuotes bool
+
+	// AnsiCQuotes makes $'...' behave like a single-quoted string in which
+	// backslash escapes are decoded, as in bash. The recognized escapes are
+	// \a, \b, \e, \E, \f, \n, \r, \t, \v, \\, \', \" and \?, one to three
+	// octal digits, \x followed by one or two hex digits, \u and \U followed
+	// by up to four and eight hex digits, and \c followed by a character
+	// giving the corresponding control character. Anything else, including
+	// escapes for invalid code points, is kept as is.
+	AnsiCQuotes bool
+
+	// SSHTokens makes splitting fail with InvalidSSHTokenError if the input
+	// contains a % that doesn't start one of the tokens OpenSSH expands in
+	// ProxyCommand and Match exec values, %% or a % followed by one of the
+	// letters C, d, h, i, j, k, L, l, n, p, r, T and u. Tokens are kept in
+	// the words as they are. Use QuoteOptions.SSHTokens to quote such
+	// words.
+	SSHTokens bool
+
+	// Substitutions makes command substitutions, $(...) and `...`, and
+	// outside of double-quotes process substitutions, <(...) and >(...),
+	// part of the word they appear in, including any whitespace inside
+	// them. Their content is kept as is. The end of a substitution is found
+	// using sh's quoting rules, counting parentheses, so a case pattern
+	// without an opening parenthesis ends it early. A substitution without
+	// an end fails with UnterminatedSubstitutionError.
+	Substitutions bool
+
+	// MaxSubstitutionDepth, if positive, limits how deeply substitutions
+	// may be nested when Substitutions is set. A substitution nested deeper
+	// fails with SubstitutionTooDeepError at its offset.
+	MaxSubstitutionDepth int
+
+	// TransformWord, if set, is called with each word found, including the
+	// unsplit remainder once Limit is reached. It returns the value to use
+	// instead, or false to drop the word. Limit counts the words kept. When
+	// a word's value is changed, the Segments of its Token are cleared.
+	TransformWord func(Token) (string, bool)
 }
 
 func DefaultSplitOptions() *SplitOptions {
@@ -47,91 +253,465 @@ func NoEscapeSplitOptions() *SplitOptions {
 	return opts
 }
 
+// Clone returns a copy of the options that can be modified independently.
+func (opts *SplitOptions) Clone() *SplitOptions {
+	c := *opts
+	return &c
+}
+
+// Validate reports whether the options describe a consistent set of rules.
+// The quote and escape characters must be valid runes that differ from each
+// other and from the split characters. The returned error wraps
+// InvalidOptionsError.
+func (opts *SplitOptions) Validate() error {
+	chars := []struct {
+		name string
+		c    rune
+	}{
+		{"SingleChar", opts.SingleChar},
+		{"DoubleChar", opts.DoubleChar},
+		{"EscapeChar", opts.EscapeChar},
+		{"CommentChar", opts.CommentChar},
+	}
+	for i, ch := range chars {
+		if ch.c == 0 {
+			continue
+		}
+		if !utf8.ValidRune(ch.c) {
+			return fmt.Errorf("%w: %s is not a valid rune", InvalidOptionsError, ch.name)
+		}
+		if opts.isSplitChar(ch.c) {
+			return fmt.Errorf("%w: %s %q is also a split character", InvalidOptionsError, ch.name, ch.c)
+		}
+		for _, other := range chars[:i] {
+			if other.c == ch.c {
+				return fmt.Errorf("%w: %s and %s are both %q", InvalidOptionsError, other.name, ch.name, ch.c)
+			}
+		}
+	}
+	if !utf8.ValidString(opts.SplitChars) {
+		return fmt.Errorf("%w: SplitChars is not valid UTF-8", InvalidOptionsError)
+	}
+	return nil
+}
+
+// isSplitChar reports whether c separates words.
+func (opts *SplitOptions) isSplitChar(c rune) bool {
+	if opts.SplitFunc != nil {
+		return opts.SplitFunc(c)
+	}
+	return strings.ContainsRune(opts.SplitChars, c)
+}
+
 // SplitWithOptions splits a string according to /bin/sh's word-splitting rules and
 // the options given.
-// It supports backslash-escapes, single-quotes, and double-quotes. Notably it does
-// not support the $'' style of quoting. It also doesn't attempt to perform any
-// other sort of expansion, including brace expansion, shell expansion, or
+// It supports backslash-escapes, single-quotes, and double-quotes. The $'...' style
+// of quoting is only supported if AnsiCQuotes is set. It doesn't attempt to perform
+// any sort of expansion, including brace expansion, shell expansion, or
 // pathname expansion.
 //
 // If the given input has an unterminated quoted string or ends in a
 // backslash-escape, one of UnterminatedSingleQuoteError,
-// UnterminatedDoubleQuoteError, or UnterminatedEscapeError is returned.
+// UnterminatedDoubleQuoteError, or UnterminatedEscapeError is returned, or
+// a *SplitError wrapping it if DetailedErrors is set.
 func SplitWithOptions(input string, opts *SplitOptions) (words []string, err error) {
+	tokens, err := newSplitter(input, opts, false).split(input)
+	words = make([]string, len(tokens))
+	for i, tok := range tokens {
+		words[i] = tok.Value
+	}
+	return words, err
+}
+
+func Split(input string) (words []string, err error) {
+	return SplitWithOptions(input, DefaultSplitOptions())
+}
+
+func SplitN(input string, n int) (words []string, err error) {
+	opts := DefaultSplitOptions()
+	opts.Limit = n
+	return SplitWithOptions(input, opts)
+}
+
+// splitter holds the state of a single SplitWithOptions call.
+type splitter struct {
+	opts  *SplitOptions
+	input string
+	size  int
+	// splitChars are the characters skipped between words, which default
+	// to DefaultSplitChars if the options have neither SplitChars nor
+	// SplitFunc.
+	splitChars string
+	buf        bytes.Buffer
+	errs       []error
+	// unterminated holds the offsets of substitutions already found to be
+	// unterminated while collecting errors, so they aren't scanned again.
+	unterminated map[int]bool
+
+	// track enables recording the segments of each word.
+	track    bool
+	segments []Segment
+
+	// stats, if set, collects statistics about the words, using the kinds
+	// of quotes and number of escapes seen in the current one, and the
+	// nesting of substitutions.
+	stats     *Stats
+	quotes    quoteKind
+	escapes   int
+	depth     int
+	openParen bool
+	backquote bool
+}
+
+func newSplitter(input string, opts *SplitOptions, track bool) *splitter {
 	if opts == nil {
 		opts = DefaultSplitOptions()
+	} else {
+		opts = opts.Clone()
+	}
+	s := &splitter{opts: opts, input: input, size: len(input), splitChars: opts.SplitChars, track: track}
+	if len(s.splitChars) == 0 {
+		s.splitChars = DefaultSplitChars
+	}
+	return s
+}
+
+func (s *splitter) split(input string) (tokens []Token, err error) {
+	opts := s.opts
+	tokens = make([]Token, 0)
+
+	if opts.RejectUTF16 && (strings.HasPrefix(input, "\xff\xfe") || strings.HasPrefix(input, "\xfe\xff")) {
+		return tokens, s.errorAt(UTF16Input, UTF16InputError, 0)
+	}
+	if opts.MaxLength > 0 && len(input) > opts.MaxLength {
+		return tokens, s.errorAt(TooLong, InputTooLongError, opts.MaxLength)
+	}
+	if opts.RejectNUL {
+		for i := 0; i < len(input); i++ {
+			if input[i] != 0 {
+				continue
+			}
+			if err := s.errorAt(NULByte, NULByteError, i); !s.collect(err) {
+				return tokens, err
+			}
+		}
+	}
+
+	if opts.SSHTokens {
+		for i := strings.IndexByte(input, '%'); i >= 0; i = nextIndexByte(input, '%', i) {
+			if sshTokenLen(input[i:]) > 0 {
+				i++
+				continue
+			}
+			if err := s.errorAt(InvalidSSHToken, InvalidSSHTokenError, i); !s.collect(err) {
+				return tokens, err
+			}
+		}
 	}
 
-	splitChars := opts.SplitChars
-	if len(splitChars) == 0 {
-		splitChars = DefaultSplitChars
+	if opts.StripBOM {
+		input = strings.TrimPrefix(input, "\ufeff")
 	}
 
 	switch opts.Limit {
 	case 0:
-		words = []string{}
 		return
 	case 1:
-		words = []string{}
-		input = strings.TrimLeft(strings.TrimRight(input, splitChars), splitChars)
-		if len(input) > 0 {
-			words = append(words, input)
+		input = strings.TrimLeftFunc(input, s.isSeparator)
+		if rest := strings.TrimRightFunc(input, s.isSeparator); len(rest) > 0 {
+			if err = s.checkRemainder(rest); err != nil {
+				return
+			}
+			tokens = s.add(tokens, s.rawToken(input, rest))
 		}
-		return
+		return tokens, errors.Join(s.errs...)
+	}
+
+	for {
+		if input = s.skipSeparators(input); len(input) == 0 {
+			break
+		}
+
+		var tok Token
+		tok, input, err = s.splitWord(input)
+		if err != nil {
+			return
+		}
+		tokens = s.add(tokens, tok)
+		if opts.Limit == len(tokens)+1 {
+			input = strings.TrimLeftFunc(input, s.isSeparator)
+			if rest := strings.TrimRightFunc(input, s.isSeparator); len(rest) > 0 {
+				if err = s.checkRemainder(rest); err != nil {
+					return
+				}
+				tokens = s.add(tokens, s.rawToken(input, rest))
+			}
+			break
+		}
+	}
+	return tokens, errors.Join(s.errs...)
+}
+
+// add appends tok to tokens, passing it through TransformWord if set.
+func (s *splitter) add(tokens []Token, tok Token) []Token {
+	if s.stats != nil {
+		s.stats.add(tok, s.quotes, s.escapes)
+	}
+	s.quotes, s.escapes = 0, 0
+	if s.opts.TransformWord == nil {
+		return append(tokens, tok)
+	}
+	value, keep := s.opts.TransformWord(tok)
+	if !keep {
+		return tokens
+	}
+	if value != tok.Value {
+		tok.Value, tok.Segments = value, nil
 	}
+	return append(tokens, tok)
+}
 
-	var buf bytes.Buffer
-	words = make([]string, 0)
+// isSeparator reports whether c is skipped between words.
+func (s *splitter) isSeparator(c rune) bool {
+	if s.opts.SplitFunc != nil {
+		return s.opts.SplitFunc(c)
+	}
+	return strings.ContainsRune(s.splitChars, c)
+}
 
+// skipSeparators returns input without any leading split characters,
+// escaped newlines and comments.
+func (s *splitter) skipSeparators(input string) string {
 	for len(input) > 0 {
-		// skip any splitChars at the start
 		c, l := utf8.DecodeRuneInString(input)
-		if strings.ContainsRune(splitChars, c) {
+		if s.isSeparator(c) {
 			input = input[l:]
 			continue
-		} else if c == opts.EscapeChar {
-			// Look ahead for escaped newline so we can skip over it
-			next := input[l:]
-			if len(next) == 0 {
-				err = UnterminatedEscapeError
-				return
+		} else if c == s.opts.CommentChar && c != 0 {
+			if i := strings.IndexByte(input, '\n'); i >= 0 {
+				input = input[i:]
+				if !s.isSeparator('\n') {
+					input = input[1:]
+				}
+			} else {
+				input = ""
 			}
+			continue
+		} else if c == s.opts.EscapeChar {
+			// Look ahead for escaped newline so we can skip over it, unless
+			// UnquotedEscapeChars keeps the escape character literal
+			next := input[l:]
 			c2, l2 := utf8.DecodeRuneInString(next)
-			if c2 == '\n' {
+			if len(next) > 0 && c2 == '\n' && (s.opts.UnquotedEscapeChars == "" || strings.ContainsRune(s.opts.UnquotedEscapeChars, c2)) {
 				input = next[l2:]
 				continue
 			}
 		}
+		break
+	}
+	return input
+}
 
-		var word string
-		word, input, err = splitWord(input, &buf, opts)
-		if err != nil {
-			return
+// rawToken returns the unparsed text at the start of input, which must be
+// a suffix of the input, as a Token.
+func (s *splitter) rawToken(input, text string) Token {
+	start := s.offset(input)
+	tok := Token{Value: text, Start: start, End: start + len(text), Kind: classifyWord(text, text)}
+	if s.track {
+		tok.Segments = []Segment{{0, len(text), start, start + len(text)}}
+	}
+	return tok
+}
+
+// emit appends text, which was copied from the input at offset, to the
+// current word.
+func (s *splitter) emit(text string, offset int) {
+	if s.track && len(text) > 0 {
+		n := s.buf.Len()
+		s.segments = append(s.segments, Segment{n, n + len(text), offset, offset + len(text)})
+	}
+	s.buf.WriteString(text)
+}
+
+// emitDecoded appends text, which was decoded from the n bytes of input at
+// offset, to the current word.
+func (s *splitter) emitDecoded(text string, offset, n int) {
+	if s.track && len(text) > 0 {
+		l := s.buf.Len()
+		s.segments = append(s.segments, Segment{l, l + len(text), offset, offset + n})
+	}
+	s.buf.WriteString(text)
+}
+
+// token returns the current word as a Token spanning the input from start
+// to end.
+func (s *splitter) token(start, end int) Token {
+	tok := Token{Value: s.buf.String(), Start: start, End: end}
+	tok.Kind = classifyWord(tok.Value, s.input[start:end])
+	if s.track {
+		tok.Segments = s.segments
+		s.segments = nil
+	}
+	return tok
+}
+
+// offset returns the position of rest, which must be a suffix of the input,
+// within the complete input.
+func (s *splitter) offset(rest string) int {
+	return s.size - len(rest)
+}
+
+// errorAt returns the error for a problem at the given offset: a
+// *SplitError if DetailedErrors or CollectErrors is set, and err itself
+// otherwise.
+func (s *splitter) errorAt(kind ErrKind, err error, offset int) error {
+	if !s.opts.DetailedErrors && !s.opts.CollectErrors {
+		return err
+	}
+	return &SplitError{Kind: kind, Err: err, Offset: offset}
+}
+
+// collect records err if CollectErrors is set and reports whether it did,
+// in which case the caller should recover and carry on.
+func (s *splitter) collect(err error) bool {
+	if s.opts.CollectErrors {
+		s.errs = append(s.errs, err)
+	}
+	return s.opts.CollectErrors
+}
+
+// checkExpansion returns a *SplitError wrapping an *ExpansionError if
+// RejectExpansions is set and input starts with an expansion. Inside
+// double-quotes only parameter expansion and command substitution are
+// recognized.
+func (s *splitter) checkExpansion(input string, quoted bool) error {
+	if !s.opts.RejectExpansions {
+		return nil
+	}
+	if construct := expansionAt(input, quoted); construct != "" {
+		offset := s.offset(input)
+		err := s.errorAt(UnsupportedExpansion, &ExpansionError{Construct: construct, Offset: offset}, offset)
+		if !s.collect(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkRemainder is checkExpansion for the unsplit remainder returned once
+// Limit is reached. It follows the quotes and escapes in it, so that only
+// the expansions a shell would perform are rejected.
+func (s *splitter) checkRemainder(input string) error {
+	if !s.opts.RejectExpansions {
+		return nil
+	}
+	opts := s.opts
+	single, double, wordStart := false, false, true
+	for i := 0; i < len(input); {
+		c, l := utf8.DecodeRuneInString(input[i:])
+		switch {
+		case single:
+			single = c != opts.SingleChar
+		case opts.EscapeChar != 0 && c == opts.EscapeChar:
+			if i+l < len(input) {
+				_, n := utf8.DecodeRuneInString(input[i+l:])
+				l += n
+			}
+		case !double && opts.SingleChar != 0 && c == opts.SingleChar:
+			single = true
+		case opts.DoubleChar != 0 && c == opts.DoubleChar:
+			double = !double
+		case !double && wordStart && c == '~':
+			offset := s.offset(input[i:])
+			err := s.errorAt(UnsupportedExpansion, &ExpansionError{Construct: "~", Offset: offset}, offset)
+			if !s.collect(err) {
+				return err
+			}
+		case !double && opts.LocaleQuotes && c == '$' && strings.HasPrefix(input[i+l:], string(opts.DoubleChar)):
+			if err := s.checkLocaleQuote(input[i+l:]); err != nil {
+				return err
+			}
+		default:
+			if err := s.checkExpansion(input[i:], double); err != nil {
+				return err
+			}
+		}
+		wordStart = !single && !double && s.isSeparator(c)
+		i += l
+	}
+	return nil
+}
+
+// checkLocaleQuote is checkExpansion for the $"..." string whose opening
+// quote starts rest.
+func (s *splitter) checkLocaleQuote(rest string) error {
+	if !s.opts.RejectExpansions {
+		return nil
+	}
+	offset := s.offset(rest) - 1
+	err := s.errorAt(UnsupportedExpansion, &ExpansionError{Construct: "$" + string(s.opts.DoubleChar), Offset: offset}, offset)
+	if !s.collect(err) {
+		return err
+	}
+	return nil
+}
+
+// expansionAt returns the leading part of the expansion input starts with,
+// or "" if there is none.
+func expansionAt(input string, quoted bool) string {
+	if len(input) < 2 {
+		if input == "`" {
+			return input
+		}
+		return ""
+	}
+	switch input[0] {
+	case '`':
+		return "`"
+	case '<', '>':
+		if !quoted && input[1] == '(' {
+			return input[:2]
 		}
-		words = append(words, word)
-		if opts.Limit == len(words)+1 {
-			input = strings.TrimSpace(input)
-			if len(input) > 0 {
-				words = append(words, input)
+	case '$':
+		switch c := input[1]; {
+		case c == '{':
+			return "${"
+		case strings.HasPrefix(input, "$(("):
+			return "$(("
+		case c == '(':
+			return "$("
+		case strings.IndexByte("@*#?$!-0123456789", c) >= 0:
+			return input[:2]
+		case isNameStart(c):
+			i := 2
+			for i < len(input) && isNameChar(input[i]) {
+				i++
 			}
-			return
+			return input[:i]
 		}
 	}
-	return
+	return ""
 }
 
-func Split(input string) (words []string, err error) {
-	return SplitWithOptions(input, DefaultSplitOptions())
+func isNameStart(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
 }
 
-func SplitN(input string, n int) (words []string, err error) {
-	opts := DefaultSplitOptions()
-	opts.Limit = n
-	return SplitWithOptions(input, opts)
+func isNameChar(c byte) bool {
+	return isNameStart(c) || '0' <= c && c <= '9'
 }
 
-func splitWord(input string, buf *bytes.Buffer, opts *SplitOptions) (word string, remainder string, err error) {
+func (s *splitter) splitWord(input string) (tok Token, remainder string, err error) {
+	buf, opts := &s.buf, s.opts
 	buf.Reset()
+	start := s.offset(input)
+
+	if opts.RejectExpansions && strings.HasPrefix(input, "~") {
+		err := s.errorAt(UnsupportedExpansion, &ExpansionError{Construct: "~", Offset: start}, start)
+		if !s.collect(err) {
+			return Token{}, "", err
+		}
+	}
 
 raw:
 	{
@@ -140,24 +720,43 @@ raw:
 			c, l := utf8.DecodeRuneInString(cur)
 			cur = cur[l:]
 			if c == opts.SingleChar {
-				buf.WriteString(input[0 : len(input)-len(cur)-l])
+				s.emit(input[0:len(input)-len(cur)-l], s.offset(input))
 				input = cur
 				goto single
 			} else if c == opts.DoubleChar {
-				buf.WriteString(input[0 : len(input)-len(cur)-l])
+				s.emit(input[0:len(input)-len(cur)-l], s.offset(input))
 				input = cur
 				goto double
 			} else if c == opts.EscapeChar {
-				buf.WriteString(input[0 : len(input)-len(cur)-l])
+				s.emit(input[0:len(input)-len(cur)-l], s.offset(input))
 				input = cur
 				goto escape
-			} else if strings.ContainsRune(opts.SplitChars, c) {
-				buf.WriteString(input[0 : len(input)-len(cur)-l])
-				return buf.String(), cur, nil
+			} else if opts.isSplitChar(c) {
+				s.emit(input[0:len(input)-len(cur)-l], s.offset(input))
+				return s.token(start, s.offset(cur)-l), cur, nil
+			} else if c == '$' && opts.LocaleQuotes && opts.DoubleChar != 0 && strings.HasPrefix(cur, string(opts.DoubleChar)) {
+				s.emit(input[0:len(input)-len(cur)-l], s.offset(input))
+				if err := s.checkLocaleQuote(cur); err != nil {
+					return Token{}, "", err
+				}
+				input = cur[utf8.RuneLen(opts.DoubleChar):]
+				goto double
+			} else if c == '$' && opts.AnsiCQuotes && opts.SingleChar != 0 && strings.HasPrefix(cur, string(opts.SingleChar)) {
+				s.emit(input[0:len(input)-len(cur)-l], s.offset(input))
+				input = cur[utf8.RuneLen(opts.SingleChar):]
+				goto ansi
+			} else if err := s.checkExpansion(input[len(input)-len(cur)-l:], false); err != nil {
+				return Token{}, "", err
+			} else if n, err := s.substitutionLen(input[len(input)-len(cur)-l:], false); err != nil {
+				return Token{}, "", err
+			} else if n > 0 {
+				cur = cur[n-l:]
+			} else if s.stats != nil {
+				s.noteStats(input[len(input)-len(cur)-l:], false)
 			}
 		}
 		if len(input) > 0 {
-			buf.WriteString(input)
+			s.emit(input, s.offset(input))
 			input = ""
 		}
 		goto done
@@ -166,13 +765,24 @@ raw:
 escape:
 	{
 		if len(input) == 0 {
-			return "", "", UnterminatedEscapeError
+			offset := s.size - utf8.RuneLen(opts.EscapeChar)
+			err := s.errorAt(UnterminatedEscape, UnterminatedEscapeError, offset)
+			if opts.LiteralTrailingEscape || s.collect(err) {
+				s.emit(string(opts.EscapeChar), offset)
+				goto done
+			}
+			return Token{}, "", err
 		}
 		c, l := utf8.DecodeRuneInString(input)
+		if opts.UnquotedEscapeChars != "" && !strings.ContainsRune(opts.UnquotedEscapeChars, c) {
+			s.emit(string(opts.EscapeChar), s.offset(input)-utf8.RuneLen(opts.EscapeChar))
+			goto raw
+		}
+		s.escapes++
 		if c == '\n' {
 			// a backslash-escaped newline is elided from the output entirely
 		} else {
-			buf.WriteString(input[:l])
+			s.emit(input[:l], s.offset(input))
 		}
 		input = input[l:]
 	}
@@ -182,21 +792,30 @@ single:
 	{
 		i := strings.IndexRune(input, opts.SingleChar)
 		if i == -1 {
-			return "", "", UnterminatedSingleQuoteError
+			offset := s.offset(input) - utf8.RuneLen(opts.SingleChar)
+			err := s.errorAt(UnterminatedSingle, UnterminatedSingleQuoteError, offset)
+			if opts.LiteralUnmatchedQuotes || s.collect(err) {
+				s.emit(string(opts.SingleChar), offset)
+				goto raw
+			}
+			return Token{}, "", err
 		}
-		buf.WriteString(input[0:i])
+		s.emit(input[0:i], s.offset(input))
+		s.quotes |= singleQuotes
 		input = input[i+1:]
 		goto raw
 	}
 
 double:
 	{
+		start, startLen, startSegments := input, buf.Len(), len(s.segments)
 		cur := input
 		for len(cur) > 0 {
 			c, l := utf8.DecodeRuneInString(cur)
 			cur = cur[l:]
 			if c == opts.DoubleChar {
-				buf.WriteString(input[0 : len(input)-len(cur)-l])
+				s.emit(input[0:len(input)-len(cur)-l], s.offset(input))
+				s.quotes |= doubleQuotes
 				input = cur
 				goto raw
 			} else if c == opts.EscapeChar {
@@ -204,19 +823,80 @@ double:
 				c2, l2 := utf8.DecodeRuneInString(cur)
 				cur = cur[l2:]
 				if strings.ContainsRune(opts.DoubleEscapeChars, c2) {
-					buf.WriteString(input[0 : len(input)-len(cur)-l-l2])
+					s.escapes++
+					s.emit(input[0:len(input)-len(cur)-l-l2], s.offset(input))
 					if c2 == '\n' {
 						// newline is special, skip the backslash entirely
 					} else {
-						buf.WriteRune(c2)
+						s.emit(input[len(input)-len(cur)-l2:len(input)-len(cur)], s.offset(cur)-l2)
 					}
 					input = cur
 				}
+			} else if err := s.checkExpansion(input[len(input)-len(cur)-l:], true); err != nil {
+				return Token{}, "", err
+			} else if n, err := s.substitutionLen(input[len(input)-len(cur)-l:], true); err != nil {
+				return Token{}, "", err
+			} else if n > 0 {
+				cur = cur[n-l:]
+			} else if s.stats != nil {
+				s.noteStats(input[len(input)-len(cur)-l:], true)
+			}
+		}
+		offset := s.offset(start) - utf8.RuneLen(opts.DoubleChar)
+		err := s.errorAt(UnterminatedDouble, UnterminatedDoubleQuoteError, offset)
+		if opts.LiteralUnmatchedQuotes || s.collect(err) {
+			buf.Truncate(startLen)
+			if s.track {
+				s.segments = s.segments[:startSegments]
+			}
+			s.emit(string(opts.DoubleChar), offset)
+			input = start
+			goto raw
+		}
+		return Token{}, "", err
+	}
+
+ansi:
+	{
+		start, startLen, startSegments := input, buf.Len(), len(s.segments)
+		cur := input
+		for len(cur) > 0 {
+			c, l := utf8.DecodeRuneInString(cur)
+			if c == opts.SingleChar {
+				s.emit(input[0:len(input)-len(cur)], s.offset(input))
+				s.quotes |= ansiQuotes
+				input = cur[l:]
+				goto raw
+			} else if c == '\\' {
+				s.escapes++
+				s.emit(input[0:len(input)-len(cur)], s.offset(input))
+				esc := cur
+				if strings.HasPrefix(cur[l:], "c"+string(opts.SingleChar)) {
+					// \c doesn't take the closing quote as its character
+					esc = cur[:l+1]
+				}
+				text, n, _, _ := decodeEscape(esc, ansiEscapes)
+				s.emitDecoded(text, s.offset(cur), n)
+				cur = cur[n:]
+				input = cur
+			} else {
+				cur = cur[l:]
+			}
+		}
+		offset := s.offset(start) - utf8.RuneLen(opts.SingleChar) - 1
+		err := s.errorAt(UnterminatedSingle, UnterminatedSingleQuoteError, offset)
+		if opts.LiteralUnmatchedQuotes || s.collect(err) {
+			buf.Truncate(startLen)
+			if s.track {
+				s.segments = s.segments[:startSegments]
 			}
+			s.emit("$"+string(opts.SingleChar), offset)
+			input = start
+			goto raw
 		}
-		return "", "", UnterminatedDoubleQuoteError
+		return Token{}, "", err
 	}
 
 done:
-	return buf.String(), input, nil
+	return s.token(start, s.size), input, nil
 }
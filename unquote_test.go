@@ -1,8 +1,14 @@
 package shellquote
 
 import (
+	"errors"
+	"os"
+	"os/exec"
 	"reflect"
+	"strings"
 	"testing"
+	"unicode"
+	"unicode/utf8"
 )
 
 func TestSimpleSplit(t *testing.T) {
@@ -25,6 +31,18 @@ func TestErrorSplit(t *testing.T) {
 	}
 }
 
+func TestDetailedErrorSplit(t *testing.T) {
+	opts := DefaultSplitOptions()
+	opts.DetailedErrors = true
+	for _, elem := range errorSplitTest {
+		_, err := SplitWithOptions(elem.input, opts)
+		var splitErr *SplitError
+		if !errors.As(err, &splitErr) || splitErr.Err != elem.error || splitErr.Kind != elem.kind || splitErr.Offset != elem.offset {
+			t.Errorf("Input %q, got error %#v, expected %v of kind %v at offset %d", elem.input, err, elem.error, elem.kind, elem.offset)
+		}
+	}
+}
+
 var simpleSplitTest = []struct {
 	input  string
 	output []string
@@ -44,12 +62,466 @@ var simpleSplitTest = []struct {
 }
 
 var errorSplitTest = []struct {
-	input string
-	error error
-}{
-	{"don't worry", UnterminatedSingleQuoteError},
-	{"'test'\\''ing", UnterminatedSingleQuoteError},
-	{"\"foo'bar", UnterminatedDoubleQuoteError},
-	{"foo\\", UnterminatedEscapeError},
-	{"   \\", UnterminatedEscapeError},
+	input  string
+	error  error
+	kind   ErrKind
+	offset int
+}{
+	{"don't worry", UnterminatedSingleQuoteError, UnterminatedSingle, 3},
+	{"'test'\\''ing", UnterminatedSingleQuoteError, UnterminatedSingle, 8},
+	{"\"foo'bar", UnterminatedDoubleQuoteError, UnterminatedDouble, 0},
+	{"foo\\", UnterminatedEscapeError, UnterminatedEscape, 3},
+	{"   \\", UnterminatedEscapeError, UnterminatedEscape, 3},
+}
+
+func TestSplitOptionsClone(t *testing.T) {
+	opts := DefaultSplitOptions()
+	c := opts.Clone()
+	c.Limit = 2
+	c.EscapeChar = 0
+	if opts.Limit != -1 || opts.EscapeChar != DefaultEscapeChar {
+		t.Errorf("modifying a clone changed the original options")
+	}
+}
+
+func TestSplitOptionsValidate(t *testing.T) {
+	for _, elem := range validateTest {
+		err := elem.opts.Validate()
+		if elem.valid && err != nil {
+			t.Errorf("Options %+v, got error %v", elem.opts, err)
+		} else if !elem.valid && !errors.Is(err, InvalidOptionsError) {
+			t.Errorf("Options %+v, got error %v, expected InvalidOptionsError", elem.opts, err)
+		}
+	}
+}
+
+var validateTest = []struct {
+	opts  *SplitOptions
+	valid bool
+}{
+	{DefaultSplitOptions(), true},
+	{NoEscapeSplitOptions(), true},
+	{&SplitOptions{}, true},
+	{&SplitOptions{SingleChar: '"', DoubleChar: '"'}, false},
+	{&SplitOptions{SplitChars: " \\", EscapeChar: '\\'}, false},
+	{&SplitOptions{SingleChar: utf8.MaxRune + 1}, false},
+	{&SplitOptions{SplitChars: "\xff"}, false},
+	{&SplitOptions{SplitChars: "'", SplitFunc: unicode.IsSpace, SingleChar: '\''}, true},
+	{&SplitOptions{SplitFunc: unicode.IsPunct, SingleChar: '\''}, false},
+}
+
+func TestSplitFunc(t *testing.T) {
+	opts := DefaultSplitOptions()
+	opts.SplitFunc = func(r rune) bool { return unicode.IsSpace(r) || r == ',' }
+	for _, elem := range splitFuncTest {
+		output, err := SplitWithOptions(elem.input, opts)
+		if err != nil {
+			t.Errorf("Input %q, got error %v", elem.input, err)
+		} else if !reflect.DeepEqual(output, elem.output) {
+			t.Errorf("Input %q, got %q, expected %q", elem.input, output, elem.output)
+		}
+	}
+
+	opts.Limit = 1
+	if output, err := SplitWithOptions("\u00a0,a, b,\u2003", opts); err != nil || !reflect.DeepEqual(output, []string{"a, b"}) {
+		t.Errorf("got %q (%v) with Limit 1", output, err)
+	}
+	opts.Limit = 2
+	if output, err := SplitWithOptions("a,,b,c,", opts); err != nil || !reflect.DeepEqual(output, []string{"a", "b,c"}) {
+		t.Errorf("got %q (%v) with Limit 2", output, err)
+	}
+	opts.SplitFunc = func(r rune) bool { return r == ',' }
+	if output, err := SplitWithOptions("a,, b c ,", opts); err != nil || !reflect.DeepEqual(output, []string{"a", " b c "}) {
+		t.Errorf("got %q (%v) with Limit 2 and only commas separating words", output, err)
+	}
+}
+
+var splitFuncTest = []struct {
+	input  string
+	output []string
+}{
+	{"a,b c", []string{"a", "b", "c"}},
+	{"\u00a0a,,\u2003b\u3000", []string{"a", "b"}},
+	{`'a,b' "c d",e\,f`, []string{"a,b", "c d", "e,f"}},
+	{"a,#comment\nb", []string{"a", "#comment", "b"}},
+}
+
+func TestRejectExpansions(t *testing.T) {
+	opts := DefaultSplitOptions()
+	opts.RejectExpansions = true
+	for _, elem := range rejectExpansionsTest {
+		output, err := SplitWithOptions(elem.input, opts)
+		if elem.construct == "" {
+			if err != nil {
+				t.Errorf("Input %q, got error %v", elem.input, err)
+			} else if !reflect.DeepEqual(output, elem.output) {
+				t.Errorf("Input %q, got %q, expected %q", elem.input, output, elem.output)
+			}
+			continue
+		}
+		var expErr *ExpansionError
+		if !errors.As(err, &expErr) {
+			t.Errorf("Input %q, got error %v, expected *ExpansionError", elem.input, err)
+		} else if expErr.Construct != elem.construct || expErr.Offset != elem.offset {
+			t.Errorf("Input %q, got %s at %d, expected %s at %d", elem.input, expErr.Construct, expErr.Offset, elem.construct, elem.offset)
+		}
+	}
+}
+
+var rejectExpansionsTest = []struct {
+	input     string
+	output    []string
+	construct string
+	offset    int
+}{
+	{"echo $HOME/bin", nil, "$HOME", 5},
+	{"echo \"a ${x}\"", nil, "${", 8},
+	{"echo a$(date)", nil, "$(", 6},
+	{"echo $((1+2))", nil, "$((", 5},
+	{"echo `date`", nil, "`", 5},
+	{"echo \"$1\"", nil, "$1", 6},
+	{"diff <(a) b", nil, "<(", 5},
+	{"ls ~/src", nil, "~", 3},
+	{"echo '$HOME' \\$x \"\\$y\" a~b", []string{"echo", "$HOME", "$x", "$y", "a~b"}, "", 0},
+	{"echo $ 5$ * {a,b}", []string{"echo", "$", "5$", "*", "{a,b}"}, "", 0},
+	{"echo \"<(x)\"", []string{"echo", "<(x)"}, "", 0},
+}
+
+func TestRejectExpansionsLimit(t *testing.T) {
+	opts := DefaultSplitOptions()
+	opts.RejectExpansions = true
+	for _, elem := range rejectExpansionsLimitTest {
+		opts.Limit = elem.limit
+		output, err := SplitWithOptions(elem.input, opts)
+		if elem.construct == "" {
+			if err != nil {
+				t.Errorf("Input %q, limit %d, got error %v", elem.input, elem.limit, err)
+			} else if !reflect.DeepEqual(output, elem.output) {
+				t.Errorf("Input %q, limit %d, got %q, expected %q", elem.input, elem.limit, output, elem.output)
+			}
+			continue
+		}
+		var expErr *ExpansionError
+		if !errors.As(err, &expErr) {
+			t.Errorf("Input %q, limit %d, got error %v, expected *ExpansionError", elem.input, elem.limit, err)
+		} else if expErr.Construct != elem.construct || expErr.Offset != elem.offset {
+			t.Errorf("Input %q, limit %d, got %s at %d, expected %s at %d", elem.input, elem.limit, expErr.Construct, expErr.Offset, elem.construct, elem.offset)
+		}
+	}
+}
+
+var rejectExpansionsLimitTest = []struct {
+	input     string
+	limit     int
+	output    []string
+	construct string
+	offset    int
+}{
+	{"echo $HOME", 1, nil, "$HOME", 5},
+	{"  $(date)", 1, nil, "$(", 2},
+	{"echo a \"${x}\"", 2, nil, "${", 8},
+	{"echo a ~/src", 2, nil, "~", 7},
+	{"echo a b<(x)", 2, nil, "<(", 8},
+	{"echo a '$HOME' \\$x a~b", 2, []string{"echo", "a '$HOME' \\$x a~b"}, "", 0},
+	{"echo \"a\" '`b`' \"'$c'\"", 1, nil, "$c", 17},
+	{"echo $HOME", 0, []string{}, "", 0},
+}
+
+func TestLiteralUnmatchedQuotes(t *testing.T) {
+	opts := DefaultSplitOptions()
+	opts.LiteralUnmatchedQuotes = true
+	for _, elem := range literalUnmatchedQuotesTest {
+		output, err := SplitWithOptions(elem.input, opts)
+		if err != nil {
+			t.Errorf("Input %q, got error %v", elem.input, err)
+		} else if !reflect.DeepEqual(output, elem.output) {
+			t.Errorf("Input %q, got %q, expected %q", elem.input, output, elem.output)
+		}
+	}
+}
+
+var literalUnmatchedQuotesTest = []struct {
+	input  string
+	output []string
+}{
+	{"don't worry", []string{"don't", "worry"}},
+	{"'test'\\''ing", []string{"test''ing"}},
+	{"say \"hello world", []string{"say", "\"hello", "world"}},
+	{"\"foo'bar", []string{"\"foo'bar"}},
+	{"\"a\\\"b c", []string{"\"a\"b", "c"}},
+	{"'a b' \"c d\" it's", []string{"a b", "c d", "it's"}},
+}
+
+func TestLiteralTrailingEscape(t *testing.T) {
+	opts := DefaultSplitOptions()
+	opts.LiteralTrailingEscape = true
+	for _, elem := range literalTrailingEscapeTest {
+		output, err := SplitWithOptions(elem.input, opts)
+		if err != nil {
+			t.Errorf("Input %q, got error %v", elem.input, err)
+		} else if !reflect.DeepEqual(output, elem.output) {
+			t.Errorf("Input %q, got %q, expected %q", elem.input, output, elem.output)
+		}
+	}
+	if _, err := SplitWithOptions("\"foo\\", opts); err != UnterminatedDoubleQuoteError {
+		t.Errorf("got error %v, expected UnterminatedDoubleQuoteError", err)
+	}
+}
+
+var literalTrailingEscapeTest = []struct {
+	input  string
+	output []string
+}{
+	{"foo\\", []string{"foo\\"}},
+	{"   \\", []string{"\\"}},
+	{"cd C:\\Temp\\", []string{"cd", "C:Temp\\"}},
+	{"a\\ b\\", []string{"a b\\"}},
+}
+
+func TestCollectErrors(t *testing.T) {
+	opts := DefaultSplitOptions()
+	opts.CollectErrors = true
+	opts.RejectExpansions = true
+	input := "echo $HOME 'a b \"c d"
+	output, err := SplitWithOptions(input, opts)
+	if expected := []string{"echo", "$HOME", "'a", "b", "\"c", "d"}; !reflect.DeepEqual(output, expected) {
+		t.Errorf("got %q, expected %q", output, expected)
+	}
+	if !errors.Is(err, UnterminatedSingleQuoteError) || !errors.Is(err, UnterminatedDoubleQuoteError) {
+		t.Errorf("got error %v, expected both unterminated quote errors", err)
+	}
+	var expErr *ExpansionError
+	if !errors.As(err, &expErr) || expErr.Offset != 5 {
+		t.Errorf("got error %v, expected an expansion error at offset 5", err)
+	}
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("got error %#v, expected a joined error", err)
+	}
+	var offsets []int
+	for _, e := range joined.Unwrap() {
+		switch e := e.(type) {
+		case *SplitError:
+			offsets = append(offsets, e.Offset)
+		case *ExpansionError:
+			offsets = append(offsets, e.Offset)
+		}
+	}
+	if expected := []int{5, 11, 16}; !reflect.DeepEqual(offsets, expected) {
+		t.Errorf("got offsets %v, expected %v", offsets, expected)
+	}
+
+	output, err = SplitWithOptions("a b\\", opts)
+	var splitErr *SplitError
+	if !reflect.DeepEqual(output, []string{"a", "b\\"}) || !errors.As(err, &splitErr) || splitErr.Offset != 3 {
+		t.Errorf("got %q and error %v, expected a trailing escape error at offset 3", output, err)
+	}
+
+	output, err = SplitWithOptions("a 'b' c", opts)
+	if err != nil || !reflect.DeepEqual(output, []string{"a", "b", "c"}) {
+		t.Errorf("got %q and error %v", output, err)
+	}
+}
+
+func TestSplitErrorKinds(t *testing.T) {
+	opts := DefaultSplitOptions()
+	opts.DetailedErrors = true
+	opts.RejectExpansions = true
+	opts.RejectNUL = true
+	for _, elem := range splitErrorKindTest {
+		_, err := SplitWithOptions(elem.input, opts)
+		var splitErr *SplitError
+		if !errors.As(err, &splitErr) {
+			t.Errorf("Input %q, got error %#v, expected a *SplitError", elem.input, err)
+		} else if splitErr.Kind != elem.kind || splitErr.Offset != elem.offset {
+			t.Errorf("Input %q, got %v at %d, expected %v at %d", elem.input, splitErr.Kind, splitErr.Offset, elem.kind, elem.offset)
+		}
+	}
+
+	opts.MaxLength = 4
+	_, err := SplitWithOptions("a b c", opts)
+	if !errors.Is(err, InputTooLongError) {
+		t.Errorf("got error %v, expected InputTooLongError", err)
+	}
+	if err.Error() != "Input too long at offset 4" {
+		t.Errorf("got error message %q", err.Error())
+	}
+}
+
+var splitErrorKindTest = []struct {
+	input  string
+	kind   ErrKind
+	offset int
+}{
+	{"a $b", UnsupportedExpansion, 2},
+	{"a\x00b", NULByte, 1},
+	{"'a\x00", NULByte, 2},
+	{"a 'b", UnterminatedSingle, 2},
+}
+
+func TestErrKindString(t *testing.T) {
+	if s := UnterminatedDouble.String(); s != "UnterminatedDouble" {
+		t.Errorf("got %q", s)
+	}
+	if s := ErrKind(99).String(); s != "ErrKind(99)" {
+		t.Errorf("got %q", s)
+	}
+}
+
+func TestByteOrderMark(t *testing.T) {
+	opts := DefaultSplitOptions()
+	input := "\ufeffecho  hi"
+	if output, _ := SplitWithOptions(input, opts); output[0] != "\ufeffecho" {
+		t.Errorf("got %q without StripBOM", output)
+	}
+	opts.StripBOM = true
+	tokens, err := SplitTokens(input, opts)
+	if err != nil || len(tokens) != 2 || tokens[0].Value != "echo" || tokens[0].Start != 3 || tokens[1].Start != 9 {
+		t.Errorf("got %+v (%v), expected echo at 3 and hi at 9", tokens, err)
+	}
+
+	opts.RejectUTF16 = true
+	for _, input := range []string{"\xff\xfee\x00c\x00", "\xfe\xff\x00e\x00c"} {
+		if _, err := SplitWithOptions(input, opts); !errors.Is(err, UTF16InputError) {
+			t.Errorf("Input %q, got error %v, expected UTF16InputError", input, err)
+		}
+	}
+}
+
+func TestUnquotedEscapeChars(t *testing.T) {
+	opts := DefaultSplitOptions()
+	opts.UnquotedEscapeChars = "\\'\" \t"
+	for _, elem := range unquotedEscapeCharsTest {
+		tokens, err := SplitTokens(elem.input, opts)
+		var words []string
+		for _, tok := range tokens {
+			words = append(words, tok.Value)
+		}
+		if err != nil {
+			t.Errorf("Input %q, got error %v", elem.input, err)
+		} else if !reflect.DeepEqual(words, elem.output) {
+			t.Errorf("Input %q, got %q, expected %q", elem.input, words, elem.output)
+		} else if offset := tokens[len(tokens)-1].InputOffset(1); offset != tokens[len(tokens)-1].Start+1 {
+			t.Errorf("Input %q, second byte of the last word maps to %d", elem.input, offset)
+		}
+	}
+
+	if _, err := SplitWithOptions(`C:\dir\`, opts); !errors.Is(err, UnterminatedEscapeError) {
+		t.Errorf("got error %v for a trailing backslash, expected UnterminatedEscapeError", err)
+	}
+}
+
+var unquotedEscapeCharsTest = []struct {
+	input  string
+	output []string
+}{
+	{`copy C:\Users\me\a.txt D:\\`, []string{`copy`, `C:\Users\me\a.txt`, `D:\`}},
+	{`a\ b \"c\" \'d \\e \$f`, []string{`a b`, `"c"`, `'d`, `\e`, `\$f`}},
+	{"a\\\nb", []string{`a\`, "b"}},
+	{"a \\\nb", []string{"a", `\`, "b"}},
+	{"\\\nb", []string{`\`, "b"}},
+	{`\x"y z"`, []string{`\xy z`}},
+}
+
+func TestLocaleQuotes(t *testing.T) {
+	opts := DefaultSplitOptions()
+	opts.LocaleQuotes = true
+	output, err := SplitWithOptions("printf $\"Hello, %s\\n\" \"$USER\" a$\"b c\"d", opts)
+	if expected := []string{"printf", "Hello, %s\\n", "$USER", "ab cd"}; err != nil || !reflect.DeepEqual(output, expected) {
+		t.Errorf("got %q (%v), expected %q", output, err, expected)
+	}
+
+	opts.RejectExpansions = true
+	_, err = SplitWithOptions("echo a$\"b\"", opts)
+	var expErr *ExpansionError
+	if !errors.As(err, &expErr) || expErr.Construct != "$\"" || expErr.Offset != 6 {
+		t.Errorf("got error %v, expected an expansion error for $\" at offset 6", err)
+	}
+}
+
+func TestAnsiCQuotes(t *testing.T) {
+	opts := DefaultSplitOptions()
+	opts.AnsiCQuotes = true
+	for _, elem := range ansiCQuotesTest {
+		output, err := SplitWithOptions(elem.input, opts)
+		if err != nil {
+			t.Errorf("Input %q, got error %v", elem.input, err)
+		} else if !reflect.DeepEqual(output, elem.output) {
+			t.Errorf("Input %q, got %q, expected %q", elem.input, output, elem.output)
+		}
+	}
+
+	opts.DetailedErrors = true
+	_, err := SplitWithOptions("echo $'abc", opts)
+	var splitErr *SplitError
+	if !errors.As(err, &splitErr) || splitErr.Kind != UnterminatedSingle || splitErr.Offset != 5 {
+		t.Errorf("got error %v, expected an unterminated single-quote at offset 5", err)
+	}
+	opts.LiteralUnmatchedQuotes = true
+	output, _ := SplitWithOptions("echo $'a b", opts)
+	if expected := []string{"echo", "$'a", "b"}; !reflect.DeepEqual(output, expected) {
+		t.Errorf("got %q, expected %q", output, expected)
+	}
+}
+
+func TestAnsiCQuotesAgainstBash(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not found")
+	}
+	opts := DefaultSplitOptions()
+	opts.AnsiCQuotes = true
+	for _, elem := range ansiCQuotesTest {
+		cmd := exec.Command(bash, "-c", `eval "set -- $1"; printf '%s\0' "$@"`, "bash", elem.input)
+		cmd.Env = append(os.Environ(), "LC_ALL=C.UTF-8")
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+		if got, _ := SplitWithOptions(elem.input, opts); !reflect.DeepEqual(got, want) {
+			t.Errorf("Input %q, bash gives %q, got %q", elem.input, want, got)
+		}
+	}
+}
+
+func TestTransformWord(t *testing.T) {
+	opts := DefaultSplitOptions()
+	opts.TransformWord = func(tok Token) (string, bool) {
+		if strings.HasPrefix(tok.Value, "--password=") {
+			return "--password=***", true
+		}
+		return tok.Value, tok.Value != "-v"
+	}
+	output, err := SplitWithOptions("login -v '--password=a b' -v user", opts)
+	if expected := []string{"login", "--password=***", "user"}; err != nil || !reflect.DeepEqual(output, expected) {
+		t.Errorf("got %q (%v), expected %q", output, err, expected)
+	}
+
+	opts.Limit = 3
+	output, _ = SplitWithOptions("a -v b -v c d", opts)
+	if expected := []string{"a", "b", "-v c d"}; !reflect.DeepEqual(output, expected) {
+		t.Errorf("got %q, expected %q", output, expected)
+	}
+
+	opts.Limit = -1
+	tokens, _ := SplitTokens("x --password=y", opts)
+	if len(tokens) != 2 || tokens[0].Segments == nil || tokens[1].Segments != nil || tokens[1].Start != 2 {
+		t.Errorf("got %+v", tokens)
+	}
+}
+
+var ansiCQuotesTest = []struct {
+	input  string
+	output []string
+}{
+	{`$'a b' x$'\t'y`, []string{"a b", "x\ty"}},
+	{`$'\a\b\e\E\f\n\r\v\\\'\"\?'`, []string{"\a\b\x1b\x1b\f\n\r\v\\'\"?"}},
+	{`$'\101\0101\7\8\x41\x4g\xg'`, []string{"A\b1\a\\8A\x04g\\xg"}},
+	{`$'\u00e9\U0001F600\u'`, []string{"\u00e9\U0001F600\\u"}},
+	{`$'\cA\c?\c\\x\q' "$'a'"`, []string{"\x01\x7f\x1cx\\q", "$'a'"}},
+	{"$'$HOME \\`x\\`' '$'", []string{"$HOME \\`x\\`", "$"}},
+	{`$'a\c' b`, []string{"a\\c", "b"}},
+	{`$'\c' $'x\c'y`, []string{"\\c", "x\\cy"}},
+	{`$'\cé'`, []string{"\x03\xa9"}},
 }
@@ -0,0 +1,113 @@
+package shellquote
+
+import (
+	"errors"
+	"strings"
+)
+
+var UnterminatedParameterError = errors.New("Unterminated parameter expansion")
+
+// VarRef is a reference to a shell variable found by ReferencedVariables.
+type VarRef struct {
+	Name string
+	// Offset is the byte offset of the $ starting the reference, and End the
+	// offset just past its end.
+	Offset, End int
+	// Braced reports whether the reference used the ${NAME} form.
+	Braced bool
+	// Operator is the parameter expansion operator following the name, like
+	// ":-" or "=", or "" if there is none.
+	Operator string
+	// Default is the unexpanded text following Operator.
+	Default string
+}
+
+// parameterOperators lists the POSIX parameter expansion operators, longest
+// first.
+var parameterOperators = []string{":-", ":=", ":?", ":+", "##", "%%", "-", "=", "?", "+", "#", "%"}
+
+// ReferencedVariables returns the variables referenced by $NAME, ${NAME}
+// and ${NAME<op>word} expansions in input, in the order they appear.
+// References inside single-quotes or escaped with a backslash are ignored,
+// as are special and positional parameters like $1 or $@. References nested
+// in the word of another expansion, as in ${A:-$B}, are returned after the
+// enclosing one. Nothing is expanded.
+//
+// If the input has an unterminated quoted string or ${ expansion, the
+// references found so far are returned with a *SplitError.
+func ReferencedVariables(input string) ([]VarRef, error) {
+	var refs []VarRef
+	var open []openParameter
+	inDouble, doubleStart := false, 0
+
+	for i := 0; i < len(input); i++ {
+		switch c := input[i]; {
+		case c == '\\':
+			i++
+		case c == '\'' && !inDouble:
+			end := strings.IndexByte(input[i+1:], '\'')
+			if end < 0 {
+				return refs, &SplitError{Kind: UnterminatedSingle, Err: UnterminatedSingleQuoteError, Offset: i}
+			}
+			i += end + 1
+		case c == '"':
+			inDouble, doubleStart = !inDouble, i
+		case c == '}' && len(open) > 0 && open[len(open)-1].quoted == inDouble:
+			ref := &refs[open[len(open)-1].ref]
+			open = open[:len(open)-1]
+			ref.End = i + 1
+			if ref.Operator != "" {
+				ref.Default = input[ref.Offset+2+len(ref.Name)+len(ref.Operator) : i]
+			}
+		case c == '$' && strings.HasPrefix(input[i:], "${"):
+			n := nameLen(input[i+2:])
+			if n == 0 {
+				// special parameters, lengths and the like
+				continue
+			}
+			ref := VarRef{Name: input[i+2 : i+2+n], Offset: i, Braced: true}
+			rest := input[i+2+n:]
+			for _, op := range parameterOperators {
+				if strings.HasPrefix(rest, op) {
+					ref.Operator = op
+					break
+				}
+			}
+			open = append(open, openParameter{ref: len(refs), quoted: inDouble})
+			refs = append(refs, ref)
+			i += 1 + n + len(ref.Operator)
+		case c == '$':
+			n := nameLen(input[i+1:])
+			if n > 0 {
+				refs = append(refs, VarRef{Name: input[i+1 : i+1+n], Offset: i, End: i + 1 + n})
+				i += n
+			}
+		}
+	}
+
+	if len(open) > 0 {
+		return refs, &SplitError{Kind: UnterminatedParameter, Err: UnterminatedParameterError, Offset: refs[open[0].ref].Offset}
+	}
+	if inDouble {
+		return refs, &SplitError{Kind: UnterminatedDouble, Err: UnterminatedDoubleQuoteError, Offset: doubleStart}
+	}
+	return refs, nil
+}
+
+// openParameter is a ${ expansion whose closing brace hasn't been seen yet.
+type openParameter struct {
+	ref    int  // index into the references found
+	quoted bool // whether it was opened inside double-quotes
+}
+
+// nameLen returns the length of the shell variable name s starts with.
+func nameLen(s string) int {
+	if len(s) == 0 || !isNameStart(s[0]) {
+		return 0
+	}
+	n := 1
+	for n < len(s) && isNameChar(s[n]) {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,59 @@
+package shellquote
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestReferencedVariables(t *testing.T) {
+	for _, elem := range referencedVariablesTest {
+		refs, err := ReferencedVariables(elem.input)
+		if err != nil {
+			t.Errorf("Input %q, got error %v", elem.input, err)
+		} else if !reflect.DeepEqual(refs, elem.refs) {
+			t.Errorf("Input %q, got %+v, expected %+v", elem.input, refs, elem.refs)
+		}
+	}
+}
+
+func TestReferencedVariablesError(t *testing.T) {
+	for _, elem := range referencedVariablesErrorTest {
+		_, err := ReferencedVariables(elem.input)
+		var splitErr *SplitError
+		if !errors.As(err, &splitErr) || splitErr.Kind != elem.kind || splitErr.Offset != elem.offset {
+			t.Errorf("Input %q, got error %v, expected %v at %d", elem.input, err, elem.kind, elem.offset)
+		}
+	}
+}
+
+var referencedVariablesTest = []struct {
+	input string
+	refs  []VarRef
+}{
+	{"echo hello", nil},
+	{"cd $HOME/src", []VarRef{{Name: "HOME", Offset: 3, End: 8}}},
+	{"echo \"${USER}@$HOST\" '$NOPE' \\$NOPE $1 $@ ${#} ${#LEN}", []VarRef{
+		{Name: "USER", Offset: 6, End: 13, Braced: true},
+		{Name: "HOST", Offset: 14, End: 19},
+	}},
+	{"ssh ${TARGET:-localhost} -p ${PORT-22}", []VarRef{
+		{Name: "TARGET", Offset: 4, End: 24, Braced: true, Operator: ":-", Default: "localhost"},
+		{Name: "PORT", Offset: 28, End: 38, Braced: true, Operator: "-", Default: "22"},
+	}},
+	{"run ${A:-\"}$B\"}x", []VarRef{
+		{Name: "A", Offset: 4, End: 15, Braced: true, Operator: ":-", Default: "\"}$B\""},
+		{Name: "B", Offset: 11, End: 13},
+	}},
+	{"echo $(cat $FILE)", []VarRef{{Name: "FILE", Offset: 11, End: 16}}},
+}
+
+var referencedVariablesErrorTest = []struct {
+	input  string
+	kind   ErrKind
+	offset int
+}{
+	{"echo ${A:-x", UnterminatedParameter, 5},
+	{"echo \"$A", UnterminatedDouble, 5},
+	{"echo '$A", UnterminatedSingle, 5},
+}
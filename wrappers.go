@@ -0,0 +1,142 @@
+package shellquote
+
+import (
+	"path"
+	"strings"
+)
+
+// Wrapper is a command that runs another one, as found by Unwrap.
+type Wrapper struct {
+	// Name is the wrapper as it appeared in the command, like sudo or
+	// /usr/bin/env.
+	Name string
+	// Args are the wrapper's own arguments: its options and operands like
+	// the duration given to timeout, the host given to ssh or the variables
+	// set by env.
+	Args []string
+}
+
+// wrapperSpec describes the command line of a wrapper.
+type wrapperSpec struct {
+	opts *OptSpec
+	// operands is the number of operands preceding the command.
+	operands int
+	// assignments is set if NAME=value operands may precede the command.
+	assignments bool
+	// niceness is set if a leading -N sets a numeric priority.
+	niceness bool
+	// remote is set if the command is passed to a shell as a single string.
+	remote bool
+	// noRun lists the options that keep the wrapper from running the
+	// command.
+	noRun []string
+	// split lists the options whose argument is split into words that
+	// precede the command.
+	split []string
+}
+
+var wrapperSpecs = map[string]wrapperSpec{
+	"sudo": {
+		opts: &OptSpec{Short: "ABbEeHiKklNnPSsVvC:D:g:h:p:R:r:T:t:U:u:",
+			Long: []string{"askpass", "background", "bell", "preserve-env", "edit", "set-home", "login", "remove-timestamp",
+				"reset-timestamp", "list", "non-interactive", "preserve-groups", "stdin", "shell", "version", "validate",
+				"close-from=", "chdir=", "group=", "host=", "prompt=", "chroot=", "role=", "command-timeout=", "type=",
+				"other-user=", "user="}},
+		noRun: []string{"e", "edit", "l", "list", "V", "version", "v", "validate"},
+	},
+	"doas":    {opts: &OptSpec{Short: "nsC:u:"}, noRun: []string{"C"}},
+	"env":     {opts: &OptSpec{Short: "0ivC:S:u:", Long: []string{"ignore-environment", "null", "debug", "chdir=", "split-string=", "unset="}}, assignments: true, split: []string{"S", "split-string"}},
+	"nice":    {opts: &OptSpec{Short: "n:", Long: []string{"adjustment="}}, niceness: true},
+	"ionice":  {opts: &OptSpec{Short: "tc:n:", Long: []string{"ignore", "class=", "classdata="}}},
+	"nohup":   {opts: &OptSpec{}},
+	"setsid":  {opts: &OptSpec{Short: "cfw", Long: []string{"ctty", "fork", "wait"}}},
+	"stdbuf":  {opts: &OptSpec{Short: "e:i:o:", Long: []string{"error=", "input=", "output="}}},
+	"time":    {opts: &OptSpec{Short: "apqvf:o:", Long: []string{"append", "portability", "quiet", "verbose", "format=", "output="}}},
+	"timeout": {opts: &OptSpec{Short: "vk:s:", Long: []string{"foreground", "preserve-status", "verbose", "kill-after=", "signal="}}, operands: 1},
+	"chroot":  {opts: &OptSpec{Long: []string{"skip-chdir", "groups=", "userspec="}}, operands: 1},
+	"command": {opts: &OptSpec{Short: "pVv"}, noRun: []string{"v", "V"}},
+	"exec":    {opts: &OptSpec{Short: "cla:"}},
+	"ssh": {
+		opts:     &OptSpec{Short: "46AaCfGgKkMNnqsTtVvXxYyB:b:c:D:E:e:F:I:i:J:L:l:m:O:o:P:p:Q:R:S:W:w:"},
+		operands: 1,
+		remote:   true,
+		noRun:    []string{"V"},
+	},
+}
+
+// Unwrap strips the commands that merely run another one, like sudo, env,
+// nice, timeout or ssh, from the front of argv, and returns them along with
+// the innermost command. The wrappers' own options are recognized, so in
+// sudo -u root env -i PATH=/bin ls, the command is ls. The words following
+// ssh's host are joined and split again, like the remote shell does.
+//
+// If a wrapper has no command to run, or has been told not to run it, as
+// with sudo -l, inner is empty. Unwrapping stops at a wrapper whose options
+// can't be parsed, which is then part of inner.
+func Unwrap(argv []string) (wrappers []Wrapper, inner []string) {
+	for len(argv) > 0 {
+		spec, ok := wrapperSpecs[path.Base(argv[0])]
+		if !ok {
+			break
+		}
+		args := argv[1:]
+		var own []string
+		if spec.niceness && len(args) > 0 && isNiceness(args[0]) {
+			own, args = args[:1], args[1:]
+		}
+		opts, operands, err := ParseArgs(args, spec.opts)
+		if err != nil {
+			break
+		}
+		own = append(own, args[:len(args)-len(operands)]...)
+
+		run := true
+		var prefix []string
+		for _, opt := range opts {
+			for _, name := range spec.noRun {
+				run = run && opt.Name != name
+			}
+			for _, name := range spec.split {
+				if opt.Name == name {
+					words, _ := Split(opt.Value)
+					prefix = append(prefix, words...)
+				}
+			}
+		}
+		operands = append(prefix, operands...)
+		n := spec.operands
+		for spec.assignments && n < len(operands) && strings.Contains(operands[n], "=") {
+			n++
+		}
+		if n > len(operands) {
+			n = len(operands)
+		}
+		if n > len(prefix) {
+			// words from a split option are already part of own
+			own = append(own, operands[len(prefix):n]...)
+		}
+		operands = operands[n:]
+		wrappers = append(wrappers, Wrapper{Name: argv[0], Args: own})
+
+		if !run {
+			return wrappers, nil
+		}
+		if spec.remote && len(operands) > 0 {
+			if words, err := Split(strings.Join(operands, " ")); err == nil {
+				operands = words
+			}
+		}
+		argv = operands
+	}
+	return wrappers, argv
+}
+
+// isNiceness reports whether arg is the obsolete -N form of nice's
+// adjustment.
+func isNiceness(arg string) bool {
+	digits := strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+	if len(digits) == len(arg) || len(digits) == 0 {
+		return false
+	}
+	return strings.Trim(digits, "0123456789") == ""
+}
@@ -0,0 +1,44 @@
+package shellquote
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnwrap(t *testing.T) {
+	for _, elem := range unwrapTest {
+		input, _ := Split(elem.input)
+		wrappers, inner := Unwrap(input)
+		if !reflect.DeepEqual(wrappers, elem.wrappers) || !reflect.DeepEqual(inner, elem.inner) {
+			t.Errorf("Input %q, got %+v %q, expected %+v %q", elem.input, wrappers, inner, elem.wrappers, elem.inner)
+		}
+	}
+}
+
+var unwrapTest = []struct {
+	input    string
+	wrappers []Wrapper
+	inner    []string
+}{
+	{"ls -l", nil, []string{"ls", "-l"}},
+	{"sudo -u root env -i PATH=/bin ls -l", []Wrapper{
+		{"sudo", []string{"-u", "root"}},
+		{"env", []string{"-i", "PATH=/bin"}},
+	}, []string{"ls", "-l"}},
+	{"/usr/bin/nice -10 nohup timeout -s KILL 5m make -j4", []Wrapper{
+		{"/usr/bin/nice", []string{"-10"}},
+		{"nohup", nil},
+		{"timeout", []string{"-s", "KILL", "5m"}},
+	}, []string{"make", "-j4"}},
+	{"ssh -p 22 host 'cd /tmp && rm -rf \"a b\"'", []Wrapper{
+		{"ssh", []string{"-p", "22", "host"}},
+	}, []string{"cd", "/tmp", "&&", "rm", "-rf", "a b"}},
+	{"env -S 'A=1 perl -w' script.pl", []Wrapper{
+		{"env", []string{"-S", "A=1 perl -w"}},
+	}, []string{"perl", "-w", "script.pl"}},
+	{"sudo -l rm", []Wrapper{{"sudo", []string{"-l"}}}, nil},
+	{"sudo", []Wrapper{{"sudo", nil}}, nil},
+	{"timeout", []Wrapper{{"timeout", nil}}, nil},
+	{"sudo --bogus rm", nil, []string{"sudo", "--bogus", "rm"}},
+	{"env -- FOO=bar -x", []Wrapper{{"env", []string{"--", "FOO=bar"}}}, []string{"-x"}},
+}